@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// startedTask is a task ecsy started during this run.
+type startedTask struct {
+	client  *ecs.Client
+	cluster string
+	taskID  string
+}
+
+var startedTasks []startedTask
+
+func trackStartedTask(client *ecs.Client, clusterName, taskID string) {
+	startedTasks = append(startedTasks, startedTask{client: client, cluster: clusterName, taskID: taskID})
+}
+
+// cleanupStartedTasks offers to stop the tasks started during this run, or
+// stops them without asking with --ephemeral.
+func cleanupStartedTasks() {
+	for _, t := range startedTasks {
+		if !ephemeral {
+			prompt := promptui.Prompt{
+				Label:     fmt.Sprintf("Stop task %s that ecsy started", t.taskID),
+				IsConfirm: true,
+				Stdin:     promptStdin(),
+			}
+			if _, err := prompt.Run(); err != nil {
+				fmt.Printf("Task %s is still running. Stop it later with 'ecsy gc'.\n", t.taskID)
+				continue
+			}
+		}
+
+		fmt.Printf("Stopping task %s...\n", t.taskID)
+		if err := stopTask(context.Background(), t.client, t.cluster, t.taskID, "ecsy session ended"); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to stop task %s: %v\n", t.taskID, err)
+		}
+	}
+	startedTasks = nil
+}
+
+func stopTask(ctx context.Context, client *ecs.Client, clusterName, taskID, reason string) error {
+	_, err := client.StopTask(ctx, &ecs.StopTaskInput{
+		Cluster: aws.String(clusterName),
+		Task:    aws.String(taskID),
+		Reason:  aws.String(reason),
+	})
+	return err
+}
+
+// gcCandidate is a task found by ecsy gc.
+type gcCandidate struct {
+	cluster string
+	task    types.Task
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Select profile and clusters
+	_, client, clusterNames, err := listClustersWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	marker := startedByMarker()
+	var candidates []gcCandidate
+	for _, clusterName := range clusterNames {
+		tasks, err := listRunningTasks(ctx, client, clusterName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list tasks in %s: %v\n", clusterName, err)
+			continue
+		}
+		for _, t := range tasks {
+			startedBy := aws.ToString(t.StartedBy)
+			if !strings.HasPrefix(startedBy, startedByPrefix) || (!gcAllUsers && startedBy != marker) {
+				continue
+			}
+			if t.StartedAt == nil || time.Since(*t.StartedAt) < gcOlderThan {
+				continue
+			}
+			candidates = append(candidates, gcCandidate{cluster: clusterName, task: t})
+		}
+	}
+
+	if len(candidates) == 0 {
+		fmt.Printf("No tasks started by ecsy older than %s.\n", gcOlderThan)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tTASK\tGROUP\tSTARTED BY\tAGE")
+	for _, c := range candidates {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.cluster, taskIDFromArn(aws.ToString(c.task.TaskArn)),
+			aws.ToString(c.task.Group), aws.ToString(c.task.StartedBy), time.Since(*c.task.StartedAt).Round(time.Minute))
+	}
+	tw.Flush()
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Stop these %d task(s)", len(candidates)),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	failed := 0
+	for _, c := range candidates {
+		taskID := taskIDFromArn(aws.ToString(c.task.TaskArn))
+		if err := stopTask(ctx, client, c.cluster, taskID, "Stopped by ecsy gc"); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to stop task %s: %v\n", taskID, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Stopped task %s\n", taskID)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to stop %d task(s)", failed)
+	}
+	return nil
+}
+
+// listRunningTasks returns the details of all running tasks in a cluster.
+func listRunningTasks(ctx context.Context, client *ecs.Client, clusterName string) ([]types.Task, error) {
+	var taskArns []string
+	var nextToken *string
+
+	for {
+		listOutput, err := client.ListTasks(ctx, &ecs.ListTasksInput{
+			Cluster:       aws.String(clusterName),
+			DesiredStatus: types.DesiredStatusRunning,
+			NextToken:     nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		taskArns = append(taskArns, listOutput.TaskArns...)
+
+		if listOutput.NextToken == nil {
+			break
+		}
+		nextToken = listOutput.NextToken
+	}
+
+	return describeTasks(ctx, client, clusterName, taskArns)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ecsy reads its own settings from ~/.ecsy/config, an INI file in the same
+// style as ~/.aws/config:
+//
+//	[profile production]
+//	record = always
+//	record_dir = ~/ecsy-recordings
+//
+//	[service api-*]
+//	shell = bash sh
+type ecsyConfig map[string]map[string]string
+
+func ecsyConfigPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ecsy", "config")
+}
+
+// loadEcsyConfig reads the config file. A missing file yields an empty
+// config.
+func loadEcsyConfig() (ecsyConfig, error) {
+	settings := ecsyConfig{}
+
+	content, err := os.ReadFile(ecsyConfigPath())
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ecsy config: %w", err)
+	}
+
+	section := ""
+	lines := strings.Split(string(content), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			if settings[section] == nil {
+				settings[section] = map[string]string{}
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == "" {
+			continue
+		}
+		settings[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return settings, nil
+}
+
+// profileSetting returns a setting from the [profile NAME] section.
+func (c ecsyConfig) profileSetting(profileName, key string) string {
+	return c["profile "+profileName][key]
+}
+
+// serviceSetting returns a setting from the [service NAME] section. NAME may
+// be a glob or /regular expression/ as with --service; an exact name takes
+// precedence over patterns, and patterns are tried in sorted order.
+func (c ecsyConfig) serviceSetting(serviceName, key string) string {
+	if value, ok := c["service "+serviceName][key]; ok {
+		return value
+	}
+
+	var patterns []string
+	for section := range c {
+		if pattern, ok := strings.CutPrefix(section, "service "); ok && isNamePattern(pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		value, ok := c["service "+pattern][key]
+		if !ok {
+			continue
+		}
+		if ok, err := matchName(pattern, serviceName); err == nil && ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// expandHome expands a leading ~/ in path.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[1:])
+	}
+	return path
+}
@@ -0,0 +1,560 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const base64LineLength = 76
+
+// remotePath is a container:path argument.
+type remotePath struct {
+	container string
+	path      string
+}
+
+// parseRemotePath reports whether arg refers to a container path. An empty
+// container name means the container is chosen as usual.
+func parseRemotePath(arg string) (remotePath, bool) {
+	i := strings.Index(arg, ":")
+	if i < 0 {
+		return remotePath{}, false
+	}
+	// Keep Windows drive letters such as C:\ local
+	if runtime.GOOS == "windows" && i == 1 {
+		return remotePath{}, false
+	}
+	if strings.ContainsAny(arg[:i], `/\`) {
+		return remotePath{}, false
+	}
+	return remotePath{container: arg[:i], path: arg[i+1:]}, true
+}
+
+func runCopy(cmd *cobra.Command, args []string) error {
+	src, srcRemote := parseRemotePath(args[0])
+	dst, dstRemote := parseRemotePath(args[1])
+	if srcRemote == dstRemote {
+		return fmt.Errorf("exactly one of the source and destination must be a container path (container:path)")
+	}
+
+	remote := dst
+	if srcRemote {
+		remote = src
+	}
+	if remote.path == "" {
+		return fmt.Errorf("missing path in container path")
+	}
+
+	ctx := context.Background()
+	target, err := selectRemoteTarget(ctx, remote.container)
+	if err != nil {
+		return err
+	}
+
+	if dstRemote {
+		return uploadPath(ctx, target, args[0], dst.path)
+	}
+	return downloadPath(ctx, target, src.path, args[1])
+}
+
+// remoteTarget is a container selected through the wizard.
+type remoteTarget struct {
+	cfg       aws.Config
+	cluster   string
+	task      string
+	container string
+}
+
+// selectRemoteTarget runs the selection wizard down to a container. A
+// non-empty containerName overrides --container.
+func selectRemoteTarget(ctx context.Context, containerName string) (remoteTarget, error) {
+	// Select profile and cluster
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return remoteTarget{}, err
+	}
+
+	// Select service
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return remoteTarget{}, fmt.Errorf("failed to select service: %w", err)
+	}
+
+	// Select task
+	selectedTask, err := selectTask(ctx, cfg, ecsClient, selectedCluster, selectedService)
+	if err != nil {
+		return remoteTarget{}, fmt.Errorf("failed to select task: %w", err)
+	}
+
+	// Select container if not specified
+	selectedContainer := containerName
+	if selectedContainer == "" {
+		selectedContainer = container
+	}
+	if selectedContainer == "" {
+		selectedContainer, err = selectContainer(ctx, ecsClient, selectedCluster, selectedTask)
+		if err != nil {
+			return remoteTarget{}, fmt.Errorf("failed to select container: %w", err)
+		}
+	}
+
+	return remoteTarget{
+		cfg:       cfg,
+		cluster:   selectedCluster,
+		task:      selectedTask,
+		container: selectedContainer,
+	}, nil
+}
+
+// uploadPath copies a local file or directory into the container.
+func uploadPath(ctx context.Context, target remoteTarget, localPath, remoteDest string) error {
+	if _, err := os.Stat(localPath); err != nil {
+		return err
+	}
+
+	// Build the archive first so its size and checksum are known
+	archive, err := os.CreateTemp("", "ecsy-cp-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	name := filepath.Base(filepath.Clean(localPath))
+	if err := writeTar(archive, localPath, name, nil); err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	size, checksum, err := fileChecksum(archive)
+	if err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf(`set -e
+t=$(mktemp); x=$(mktemp -d); trap 'rm -rf "$t" "$x"' EXIT
+base64 -d > "$t"
+if command -v sha256sum >/dev/null 2>&1; then
+  [ "$(sha256sum "$t" | cut -d' ' -f1)" = %s ] || { echo "checksum mismatch" >&2; exit 3; }
+else
+  echo "sha256sum not found, skipping checksum verification" >&2
+fi
+if [ -d %s ]; then tar xf "$t" -C %s; else tar xf "$t" -C "$x" && mv "$x"/%s %s; fi`,
+		checksum, shellQuote(remoteDest), shellQuote(remoteDest), shellQuote(name), shellQuote(remoteDest))
+
+	progress := newProgress(fmt.Sprintf("Uploading %s", name), size)
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(encodeBase64Lines(writer, io.TeeReader(archive, progress)))
+	}()
+
+	exitCode, err := runRemoteCommand(ctx, target.cfg, target.cluster, target.task, target.container, script, reader, os.Stdout, os.Stderr)
+	reader.Close()
+	progress.Done()
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return &remoteExitError{code: exitCode}
+	}
+
+	fmt.Fprintf(os.Stderr, "Copied %s to %s:%s (%s, sha256 %s)\n", localPath, target.container, remoteDest, formatBytes(size), checksum[:12])
+	return nil
+}
+
+// downloadPath copies a file or directory from the container.
+func downloadPath(ctx context.Context, target remoteTarget, remoteSrc, localDest string) error {
+	remoteSrc = path.Clean(remoteSrc)
+	name := path.Base(remoteSrc)
+
+	script := fmt.Sprintf(`set -e
+t=$(mktemp); trap 'rm -f "$t"' EXIT
+tar cf "$t" -C %s %s
+printf 'SIZE:%%s\n' "$(wc -c < "$t")"
+if command -v sha256sum >/dev/null 2>&1; then printf 'SHA256:%%s\n' "$(sha256sum "$t" | cut -d' ' -f1)"; fi
+echo DATA:
+base64 "$t"`, shellQuote(path.Dir(remoteSrc)), shellQuote(name))
+
+	archive, err := os.CreateTemp("", "ecsy-cp-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	reader, writer := io.Pipe()
+	type decodeResult struct {
+		size     int64
+		checksum string
+		err      error
+	}
+	decoded := make(chan decodeResult, 1)
+	go func() {
+		size, checksum, err := decodeDownload(reader, archive, name)
+		reader.CloseWithError(err)
+		decoded <- decodeResult{size, checksum, err}
+	}()
+
+	exitCode, err := runRemoteCommand(ctx, target.cfg, target.cluster, target.task, target.container, script, nil, writer, os.Stderr)
+	writer.Close()
+	result := <-decoded
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return &remoteExitError{code: exitCode}
+	}
+	if result.err != nil {
+		return fmt.Errorf("failed to receive archive: %w", result.err)
+	}
+
+	// Verify what was received before touching the destination
+	size, checksum, err := fileChecksum(archive)
+	if err != nil {
+		return err
+	}
+	if size != result.size {
+		return fmt.Errorf("size mismatch: expected %d bytes, received %d", result.size, size)
+	}
+	if result.checksum == "" {
+		fmt.Fprintln(os.Stderr, "sha256sum not found in the container, skipping checksum verification")
+	} else if checksum != result.checksum {
+		return fmt.Errorf("checksum mismatch: expected %s, received %s", result.checksum, checksum)
+	}
+
+	if err := extractTar(archive, name, localDest); err != nil {
+		return fmt.Errorf("failed to extract archive: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Copied %s:%s to %s (%s, sha256 %s)\n", target.container, remoteSrc, localDest, formatBytes(size), checksum[:12])
+	return nil
+}
+
+// decodeDownload parses the SIZE/SHA256 header written by the download
+// script and decodes the base64 archive that follows into w.
+func decodeDownload(r io.Reader, w io.Writer, name string) (int64, string, error) {
+	br := bufio.NewReader(r)
+	var size int64 = -1
+	var checksum string
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return 0, "", fmt.Errorf("unexpected end of output")
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "SIZE:"):
+			size, _ = strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "SIZE:")), 10, 64)
+		case strings.HasPrefix(line, "SHA256:"):
+			checksum = strings.TrimPrefix(line, "SHA256:")
+		case line == "DATA:":
+			progress := newProgress(fmt.Sprintf("Downloading %s", name), size)
+			_, err := io.Copy(io.MultiWriter(w, progress), base64.NewDecoder(base64.StdEncoding, br))
+			progress.Done()
+			return size, checksum, err
+		}
+	}
+}
+
+// writeTar archives root under name. When files is not nil only those
+// paths (relative to root, slash separated) are included.
+func writeTar(w io.Writer, root, name string, files []string) error {
+	tw := tar.NewWriter(w)
+
+	addFile := func(filePath, entryName string, info fs.FileInfo) error {
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			var err error
+			if link, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			// Sockets, devices and the like cannot be copied
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = entryName
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	}
+
+	if files != nil {
+		for _, file := range files {
+			filePath := filepath.Join(root, filepath.FromSlash(file))
+			info, err := os.Lstat(filePath)
+			if err != nil {
+				return err
+			}
+			if err := addFile(filePath, path.Join(name, file), info); err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	}
+
+	err := filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return addFile(filePath, path.Join(name, filepath.ToSlash(rel)), info)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// extractTar extracts an archive whose top-level entry is name. If dest is
+// an existing directory the entry is created inside it, otherwise it is
+// created as dest.
+func extractTar(archive *os.File, name, dest string) error {
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	root := dest
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		root = filepath.Join(dest, name)
+	}
+
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Map the top-level entry onto root and refuse paths escaping it
+		entryName := path.Clean(header.Name)
+		if entryName != name && !strings.HasPrefix(entryName, name+"/") {
+			return fmt.Errorf("unexpected entry in archive: %s", header.Name)
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(entryName, name), "/")
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("unsafe path in archive: %s", header.Name)
+		}
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		mode := os.FileMode(header.Mode).Perm()
+
+		// Never write through a symlink, such as one extracted earlier
+		if err := checkNoSymlinkParents(root, rel); err != nil {
+			return err
+		}
+		existing, err := os.Lstat(target)
+		isSymlink := err == nil && existing.Mode()&os.ModeSymlink != 0
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if isSymlink {
+				return fmt.Errorf("refusing to extract %s: %s is a symlink", header.Name, target)
+			}
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if isSymlink {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if !symlinkInside(root, target, header.Linkname) {
+				return fmt.Errorf("unsafe symlink in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkNoSymlinkParents fails if a directory between root and the entry rel
+// is a symlink. Directories that don't exist yet are fine.
+func checkNoSymlinkParents(root, rel string) error {
+	dir := root
+	parts := strings.Split(rel, "/")
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to extract %s: %s is a symlink", rel, dir)
+		}
+	}
+	return nil
+}
+
+// symlinkInside reports whether a symlink at target pointing to linkname
+// stays within root.
+func symlinkInside(root, target, linkname string) bool {
+	if linkname == "" || filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") || filepath.VolumeName(linkname) != "" {
+		return false
+	}
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// encodeBase64Lines writes r to w as base64 in lines of base64LineLength.
+func encodeBase64Lines(w io.Writer, r io.Reader) error {
+	raw := make([]byte, base64LineLength/4*3)
+	encoded := make([]byte, base64LineLength+1)
+	for {
+		n, err := io.ReadFull(r, raw)
+		if n > 0 {
+			base64.StdEncoding.Encode(encoded, raw[:n])
+			length := base64.StdEncoding.EncodedLen(n)
+			encoded[length] = '\n'
+			if _, werr := w.Write(encoded[:length+1]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// fileChecksum returns the size and SHA-256 of f and rewinds it.
+func fileChecksum(f *os.File) (int64, string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, "", err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// progress prints a transfer progress line to stderr when it is a terminal.
+type progress struct {
+	label     string
+	total     int64
+	written   int64
+	lastPrint time.Time
+	enabled   bool
+}
+
+func newProgress(label string, total int64) *progress {
+	return &progress{
+		label:   label,
+		total:   total,
+		enabled: term.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if p.enabled && time.Since(p.lastPrint) > 100*time.Millisecond {
+		p.print()
+		p.lastPrint = time.Now()
+	}
+	return len(b), nil
+}
+
+// Done prints the final state and ends the progress line.
+func (p *progress) Done() {
+	if p.enabled {
+		p.print()
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *progress) print() {
+	if p.total > 0 {
+		fmt.Fprintf(os.Stderr, "\r%s: %s / %s (%d%%)", p.label, formatBytes(p.written), formatBytes(p.total), p.written*100/p.total)
+	} else {
+		fmt.Fprintf(os.Stderr, "\r%s: %s", p.label, formatBytes(p.written))
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tarEntry is an entry of a test archive.
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+func writeTestArchive(t *testing.T, entries []tarEntry) *os.File {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "archive-*.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	tw := tar.NewWriter(f)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0644, Linkname: e.linkname}
+		if e.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+		if e.typeflag == tar.TypeReg {
+			header.Size = int64(len(e.body))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if e.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestExtractTar(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr string
+		files   map[string]string
+	}{
+		{
+			name: "directory with files and a relative symlink",
+			entries: []tarEntry{
+				{name: "app", typeflag: tar.TypeDir},
+				{name: "app/conf", typeflag: tar.TypeDir},
+				{name: "app/conf/a.txt", typeflag: tar.TypeReg, body: "a"},
+				{name: "app/b.txt", typeflag: tar.TypeReg, body: "b"},
+				{name: "app/link", typeflag: tar.TypeSymlink, linkname: "conf/a.txt"},
+			},
+			files: map[string]string{"app/conf/a.txt": "a", "app/b.txt": "b", "app/link": "a"},
+		},
+		{
+			name: "entry outside the top-level entry",
+			entries: []tarEntry{
+				{name: "app", typeflag: tar.TypeDir},
+				{name: "other/x", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: "unexpected entry",
+		},
+		{
+			name: "dot dot path",
+			entries: []tarEntry{
+				{name: "app", typeflag: tar.TypeDir},
+				{name: "app/../../x", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: "unexpected entry",
+		},
+		{
+			name: "absolute symlink",
+			entries: []tarEntry{
+				{name: "app", typeflag: tar.TypeDir},
+				{name: "app/link", typeflag: tar.TypeSymlink, linkname: "/etc"},
+			},
+			wantErr: "unsafe symlink",
+		},
+		{
+			name: "symlink escaping the destination",
+			entries: []tarEntry{
+				{name: "app", typeflag: tar.TypeDir},
+				{name: "app/link", typeflag: tar.TypeSymlink, linkname: "../../outside"},
+			},
+			wantErr: "unsafe symlink",
+		},
+		{
+			name: "file written through an extracted symlink",
+			entries: []tarEntry{
+				{name: "app", typeflag: tar.TypeDir},
+				{name: "app/sub", typeflag: tar.TypeDir},
+				{name: "app/link", typeflag: tar.TypeSymlink, linkname: "sub"},
+				{name: "app/link/x", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: "is a symlink",
+		},
+		{
+			name: "file replacing an extracted symlink",
+			entries: []tarEntry{
+				{name: "app", typeflag: tar.TypeDir},
+				{name: "app/a.txt", typeflag: tar.TypeReg, body: "a"},
+				{name: "app/link", typeflag: tar.TypeSymlink, linkname: "a.txt"},
+				{name: "app/link", typeflag: tar.TypeReg, body: "new"},
+			},
+			files: map[string]string{"app/a.txt": "a", "app/link": "new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := writeTestArchive(t, tt.entries)
+			dest := t.TempDir()
+
+			err := extractTar(archive, "app", dest)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractTar() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractTar() error = %v", err)
+			}
+			for name, want := range tt.files {
+				got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatalf("reading %s: %v", name, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractTarDoesNotFollowExistingSymlinks(t *testing.T) {
+	dest := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dest, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dest, "app", "evil")); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := writeTestArchive(t, []tarEntry{
+		{name: "app", typeflag: tar.TypeDir},
+		{name: "app/evil/x", typeflag: tar.TypeReg, body: "x"},
+	})
+	if err := extractTar(archive, "app", dest); err == nil {
+		t.Fatal("extractTar() wrote through an existing symlink")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "x")); !os.IsNotExist(err) {
+		t.Fatalf("file was created outside the destination: %v", err)
+	}
+}
+
+func TestSymlinkInside(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "dest", "app")
+	tests := []struct {
+		target   string
+		linkname string
+		want     bool
+	}{
+		{filepath.Join(root, "link"), "a.txt", true},
+		{filepath.Join(root, "sub", "link"), "../a.txt", true},
+		{filepath.Join(root, "link"), ".", true},
+		{filepath.Join(root, "link"), "..", false},
+		{filepath.Join(root, "link"), "../other", false},
+		{filepath.Join(root, "sub", "link"), "../../x", false},
+		{filepath.Join(root, "link"), "/etc/passwd", false},
+		{filepath.Join(root, "link"), "", false},
+	}
+	for _, tt := range tests {
+		if got := symlinkInside(root, tt.target, tt.linkname); got != tt.want {
+			t.Errorf("symlinkInside(%q, %q) = %t, want %t", tt.target, tt.linkname, got, tt.want)
+		}
+	}
+}
+
+func TestParseRemotePath(t *testing.T) {
+	tests := []struct {
+		arg    string
+		want   remotePath
+		remote bool
+	}{
+		{"app:/var/log/app.log", remotePath{container: "app", path: "/var/log/app.log"}, true},
+		{":/tmp/x", remotePath{path: "/tmp/x"}, true},
+		{"./local.txt", remotePath{}, false},
+		{"dir/a:b", remotePath{}, false},
+	}
+	for _, tt := range tests {
+		got, remote := parseRemotePath(tt.arg)
+		if remote != tt.remote || got != tt.want {
+			t.Errorf("parseRemotePath(%q) = %+v, %t, want %+v, %t", tt.arg, got, remote, tt.want, tt.remote)
+		}
+	}
+}
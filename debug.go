@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	debugContainerName = "ecsy-debug"
+	debugFamilySuffix  = "-ecsy-debug"
+	defaultDebugImage  = "nicolaka/netshoot"
+)
+
+func runDebug(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Select profile and cluster
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Select service
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	describeOutput, err := ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(selectedCluster),
+		Services: []string{selectedService},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	if len(describeOutput.Services) == 0 {
+		return fmt.Errorf("service not found: %s", selectedService)
+	}
+	service := describeOutput.Services[0]
+
+	taskDef, tags, err := describeTaskDefinition(ctx, ecsClient, aws.ToString(service.TaskDefinition))
+	if err != nil {
+		return err
+	}
+	if taskDef.NetworkMode != types.NetworkModeAwsvpc {
+		fmt.Printf("Warning: network mode is %s, so the toolbox does not share the network namespace of the other containers.\n", taskDef.NetworkMode)
+	}
+
+	// Pick the toolbox image
+	image := debugImage
+	if image == "" {
+		settings, err := loadEcsyConfig()
+		if err != nil {
+			return err
+		}
+		image = settings.serviceSetting(selectedService, "debug_image")
+	}
+	if image == "" {
+		image = defaultDebugImage
+	}
+
+	input, err := debugTaskDefinitionInput(taskDef, tags, image)
+	if err != nil {
+		return err
+	}
+
+	// Register a temporary revision with the toolbox container
+	registerOutput, err := ecsClient.RegisterTaskDefinition(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to register debug task definition: %w", err)
+	}
+	debugTaskDef := aws.ToString(registerOutput.TaskDefinition.TaskDefinitionArn)
+	fmt.Printf("Registered debug task definition: %s\n", debugTaskDef)
+	defer func() {
+		fmt.Println("Deregistering debug task definition...")
+		if _, err := ecsClient.DeregisterTaskDefinition(context.Background(), &ecs.DeregisterTaskDefinitionInput{
+			TaskDefinition: aws.String(debugTaskDef),
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to deregister %s: %v\n", debugTaskDef, err)
+		}
+	}()
+
+	// Run it like the service would
+	fmt.Println("Starting debug task...")
+	taskID, err := runServiceTask(ctx, ecsClient, selectedCluster, service, debugTaskDef, nil)
+	if taskID != "" {
+		defer func() {
+			fmt.Printf("Stopping debug task %s...\n", taskID)
+			if err := stopTask(context.Background(), ecsClient, selectedCluster, taskID, "ecsy debug session ended"); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to stop task %s: %v\n", taskID, err)
+			}
+		}()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start debug task: %w", err)
+	}
+
+	// Connect to the toolbox
+	container = debugContainerName
+	return executeCommand(ctx, cfg, selectedCluster, taskID, selectedService)
+}
+
+// debugTaskDefinitionInput returns a copy of taskDef in the debug family with
+// the toolbox container added, or swapped in for --replace.
+func debugTaskDefinitionInput(taskDef *types.TaskDefinition, tags []types.Tag, image string) (*ecs.RegisterTaskDefinitionInput, error) {
+	input := registerInputFromTaskDefinition(taskDef, tags)
+	input.Family = aws.String(aws.ToString(taskDef.Family) + debugFamilySuffix)
+
+	toolbox := types.ContainerDefinition{
+		Name:       aws.String(debugContainerName),
+		Image:      aws.String(image),
+		Essential:  aws.Bool(true),
+		EntryPoint: []string{"sh", "-c"},
+		Command:    []string{"trap 'exit 0' TERM; while :; do sleep 3600 & wait $!; done"},
+		LinuxParameters: &types.LinuxParameters{
+			InitProcessEnabled: aws.Bool(true),
+		},
+	}
+	if taskDef.Memory == nil {
+		// Container memory is required when the task has none
+		toolbox.MemoryReservation = aws.Int32(64)
+	}
+
+	var containers []types.ContainerDefinition
+	replaced := false
+	for _, c := range input.ContainerDefinitions {
+		name := aws.ToString(c.Name)
+		if name == debugContainerName {
+			return nil, fmt.Errorf("task definition already has a container named %s", debugContainerName)
+		}
+		if debugReplace != "" && name == debugReplace {
+			replaced = true
+			continue
+		}
+		containers = append(containers, c)
+	}
+	if debugReplace != "" {
+		if !replaced {
+			return nil, fmt.Errorf("container %s not found in task definition %s", debugReplace, aws.ToString(taskDef.TaskDefinitionArn))
+		}
+
+		// Drop dependencies on the replaced container
+		for i := range containers {
+			var dependsOn []types.ContainerDependency
+			for _, dependency := range containers[i].DependsOn {
+				if aws.ToString(dependency.ContainerName) != debugReplace {
+					dependsOn = append(dependsOn, dependency)
+				}
+			}
+			containers[i].DependsOn = dependsOn
+		}
+	}
+	input.ContainerDefinitions = append(containers, toolbox)
+
+	return input, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+func runDeploy(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	images, err := parseImageSpecs(deployImages)
+	if err != nil {
+		return err
+	}
+
+	// Select profile, cluster and service
+	_, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	describeOutput, err := ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(selectedCluster),
+		Services: []string{selectedService},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	if len(describeOutput.Services) == 0 {
+		return fmt.Errorf("service not found: %s", selectedService)
+	}
+	currentTaskDef := aws.ToString(describeOutput.Services[0].TaskDefinition)
+
+	// Copy the current revision with the new images
+	taskDef, tags, err := describeTaskDefinition(ctx, ecsClient, currentTaskDef)
+	if err != nil {
+		return err
+	}
+	input := registerInputFromTaskDefinition(taskDef, tags)
+
+	var containerNames []string
+	for i, definition := range input.ContainerDefinitions {
+		name := aws.ToString(definition.Name)
+		containerNames = append(containerNames, name)
+		key := name
+		image, ok := images[key]
+		if !ok && len(input.ContainerDefinitions) == 1 {
+			key = ""
+			image, ok = images[key]
+		}
+		if !ok {
+			continue
+		}
+		fmt.Printf("%s: %s -> %s\n", name, aws.ToString(definition.Image), image)
+		input.ContainerDefinitions[i].Image = aws.String(image)
+		delete(images, key)
+	}
+	if len(images) > 0 {
+		var unknown []string
+		for name := range images {
+			if name == "" {
+				return fmt.Errorf("--image needs a container name (name=image) because the task definition has several containers: %s", strings.Join(containerNames, ", "))
+			}
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("no container named %s in %s (containers: %s)", strings.Join(unknown, ", "), taskDefinitionName(currentTaskDef), strings.Join(containerNames, ", "))
+	}
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Deploy a new revision of %s to service %s in %s", aws.ToString(taskDef.Family), selectedService, selectedCluster),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	registerOutput, err := ecsClient.RegisterTaskDefinition(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to register task definition: %w", err)
+	}
+	newTaskDef := aws.ToString(registerOutput.TaskDefinition.TaskDefinitionArn)
+	fmt.Printf("Registered task definition %s\n", taskDefinitionName(newTaskDef))
+
+	return updateServiceTaskDefinition(ctx, ecsClient, selectedCluster, selectedService, newTaskDef)
+}
+
+// parseImageSpecs parses --image values of the form container=image. A bare
+// image is stored under "" and applies to a task definition with a single
+// container.
+func parseImageSpecs(specs []string) (map[string]string, error) {
+	images := make(map[string]string)
+	for _, spec := range specs {
+		name, image, found := strings.Cut(spec, "=")
+		if !found {
+			name, image = "", spec
+		}
+		if image == "" {
+			return nil, fmt.Errorf("invalid --image %q (want container=image)", spec)
+		}
+		if _, ok := images[name]; ok {
+			return nil, fmt.Errorf("--image given twice for container %q", name)
+		}
+		images[name] = image
+	}
+	return images, nil
+}
+
+// updateServiceTaskDefinition points a service to a task definition and
+// watches the rollout until the service is stable.
+func updateServiceTaskDefinition(ctx context.Context, client *ecs.Client, clusterName, serviceName, taskDefinition string) error {
+	started := time.Now()
+	updateOutput, err := client.UpdateService(ctx, &ecs.UpdateServiceInput{
+		Cluster:        aws.String(clusterName),
+		Service:        aws.String(serviceName),
+		TaskDefinition: aws.String(taskDefinition),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+
+	deploymentID := primaryDeploymentID(updateOutput.Service)
+	fmt.Printf("Started deployment %s\n", deploymentID)
+	return watchDeployment(ctx, client, clusterName, serviceName, deploymentID, deploymentStart(updateOutput.Service, started))
+}
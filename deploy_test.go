@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImageSpecs(t *testing.T) {
+	tests := []struct {
+		specs   []string
+		want    map[string]string
+		wantErr string
+	}{
+		{nil, map[string]string{}, ""},
+		{[]string{"nginx:1.27"}, map[string]string{"": "nginx:1.27"}, ""},
+		{
+			[]string{"web=123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/web:v2", "sidecar=envoy:1.30"},
+			map[string]string{"web": "123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/web:v2", "sidecar": "envoy:1.30"},
+			"",
+		},
+		// Only the first = separates the container name
+		{[]string{"web=repo/web:v2=x"}, map[string]string{"web": "repo/web:v2=x"}, ""},
+		{[]string{"web="}, nil, "invalid --image"},
+		{[]string{""}, nil, "invalid --image"},
+		{[]string{"web=nginx:1", "web=nginx:2"}, nil, "given twice"},
+		{[]string{"nginx:1", "nginx:2"}, nil, "given twice"},
+	}
+	for _, tt := range tests {
+		got, err := parseImageSpecs(tt.specs)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseImageSpecs(%q) error = %v, want %q", tt.specs, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseImageSpecs(%q) error = %v", tt.specs, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseImageSpecs(%q) = %v, want %v", tt.specs, got, tt.want)
+		}
+	}
+}
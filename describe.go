@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+func runDescribe(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	kind := args[0]
+	if kind != "task" && kind != "service" {
+		return fmt.Errorf("unknown resource %q (want task or service)", kind)
+	}
+	if describeFormat != "" && describeFormat != outputJSON && describeFormat != outputYAML {
+		return fmt.Errorf("unknown output format %q (want json or yaml)", describeFormat)
+	}
+
+	// An ID on the command line skips the matching prompt
+	if len(args) > 1 {
+		if kind == "task" {
+			task = args[1]
+		} else {
+			service = args[1]
+		}
+	}
+
+	// Select profile and cluster
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	if kind == "service" {
+		selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+		if err != nil {
+			return fmt.Errorf("failed to select service: %w", err)
+		}
+		return describeService(ctx, ecsClient, selectedCluster, selectedService)
+	}
+
+	// Tasks are picked through their service unless given
+	selectedTask := task
+	if selectedTask == "" {
+		selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+		if err != nil {
+			return fmt.Errorf("failed to select service: %w", err)
+		}
+		selectedTask, err = selectTask(ctx, cfg, ecsClient, selectedCluster, selectedService)
+		if err != nil {
+			return fmt.Errorf("failed to select task: %w", err)
+		}
+	}
+	return describeTask(ctx, ecsClient, selectedCluster, selectedTask)
+}
+
+// printRaw prints an SDK structure with --output json or yaml.
+func printRaw(v interface{}) error {
+	if describeFormat == outputYAML {
+		return printYAML(os.Stdout, v)
+	}
+	return printJSON(os.Stdout, v)
+}
+
+func describeTask(ctx context.Context, client *ecs.Client, clusterName, taskID string) error {
+	output, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(clusterName),
+		Tasks:   []string{taskID},
+		Include: []types.TaskField{types.TaskFieldTags},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe task: %w", err)
+	}
+	if len(output.Tasks) == 0 {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	t := output.Tasks[0]
+	if describeFormat != "" {
+		return printRaw(t)
+	}
+
+	// Port mappings are only in the task definition for awsvpc tasks
+	taskDef, _, err := describeTaskDefinition(ctx, client, aws.ToString(t.TaskDefinitionArn))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", name, value)
+		}
+	}
+
+	field("Task", taskIDFromArn(aws.ToString(t.TaskArn)))
+	field("Cluster", clusterName)
+	field("Service", serviceNameFromGroup(aws.ToString(t.Group)))
+	field("Task definition", taskDefinitionName(aws.ToString(t.TaskDefinitionArn)))
+	field("Status", fmt.Sprintf("%s (desired %s)", aws.ToString(t.LastStatus), aws.ToString(t.DesiredStatus)))
+	field("Health", string(t.HealthStatus))
+	field("Launch", joinNonEmpty(", ", taskLaunchLabel(t), prefixed("platform ", aws.ToString(t.PlatformVersion))))
+	field("CPU / memory", fmt.Sprintf("%s / %s", aws.ToString(t.Cpu), aws.ToString(t.Memory)))
+	field("Availability zone", aws.ToString(t.AvailabilityZone))
+	field("Private IP", taskPrivateIP(t))
+	field("Exec enabled", fmt.Sprint(t.EnableExecuteCommand))
+	field("Started by", aws.ToString(t.StartedBy))
+	field("Created", formatTimestamp(t.CreatedAt))
+	field("Started", formatTimestamp(t.StartedAt))
+	field("Stopped", formatTimestamp(t.StoppedAt))
+	if t.StoppedReason != nil {
+		field("Stopped reason", fmt.Sprintf("%s: %s", t.StopCode, aws.ToString(t.StoppedReason)))
+	}
+	field("Tags", formatTags(t.Tags))
+	w.Flush()
+
+	fmt.Println("\nContainers:")
+	for _, c := range t.Containers {
+		name := aws.ToString(c.Name)
+		fmt.Printf("  %s  %s", name, aws.ToString(c.LastStatus))
+		if c.HealthStatus != "" && c.HealthStatus != types.HealthStatusUnknown {
+			fmt.Printf("  %s", c.HealthStatus)
+		}
+		fmt.Println()
+
+		cw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		containerField := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(cw, "    %s:\t%s\n", name, value)
+			}
+		}
+		containerField("Image", aws.ToString(c.Image))
+		containerField("Digest", aws.ToString(c.ImageDigest))
+		containerField("Ports", containerPorts(c, taskDef, name))
+		for _, ni := range c.NetworkInterfaces {
+			containerField("IP", aws.ToString(ni.PrivateIpv4Address))
+		}
+		if c.ExitCode != nil {
+			containerField("Exit code", fmt.Sprint(*c.ExitCode))
+		}
+		containerField("Reason", aws.ToString(c.Reason))
+		cw.Flush()
+	}
+
+	return nil
+}
+
+func describeService(ctx context.Context, client *ecs.Client, clusterName, serviceName string) error {
+	output, err := client.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(clusterName),
+		Services: []string{serviceName},
+		Include:  []types.ServiceField{types.ServiceFieldTags},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	if len(output.Services) == 0 {
+		return fmt.Errorf("service not found: %s", serviceName)
+	}
+	s := output.Services[0]
+	if describeFormat != "" {
+		return printRaw(s)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", name, value)
+		}
+	}
+
+	field("Service", aws.ToString(s.ServiceName))
+	field("Cluster", clusterName)
+	field("Status", aws.ToString(s.Status))
+	field("Task definition", taskDefinitionName(aws.ToString(s.TaskDefinition)))
+	field("Tasks", fmt.Sprintf("%d desired, %d running, %d pending", s.DesiredCount, s.RunningCount, s.PendingCount))
+	field("Launch", joinNonEmpty(", ", string(s.LaunchType), prefixed("platform ", aws.ToString(s.PlatformVersion))))
+	var strategy []string
+	for _, item := range s.CapacityProviderStrategy {
+		strategy = append(strategy, fmt.Sprintf("%s (weight %d, base %d)", aws.ToString(item.CapacityProvider), item.Weight, item.Base))
+	}
+	field("Capacity providers", strings.Join(strategy, ", "))
+	field("Scheduling", string(s.SchedulingStrategy))
+	field("Exec enabled", fmt.Sprint(s.EnableExecuteCommand))
+
+	if s.NetworkConfiguration != nil && s.NetworkConfiguration.AwsvpcConfiguration != nil {
+		vpc := s.NetworkConfiguration.AwsvpcConfiguration
+		field("Subnets", strings.Join(vpc.Subnets, ", "))
+		field("Security groups", strings.Join(vpc.SecurityGroups, ", "))
+		field("Public IP", string(vpc.AssignPublicIp))
+	}
+	for _, lb := range s.LoadBalancers {
+		target := aws.ToString(lb.TargetGroupArn)
+		if target == "" {
+			target = aws.ToString(lb.LoadBalancerName)
+		}
+		field("Load balancer", fmt.Sprintf("%s -> %s:%d", target, aws.ToString(lb.ContainerName), aws.ToInt32(lb.ContainerPort)))
+	}
+	for _, registry := range s.ServiceRegistries {
+		field("Service registry", aws.ToString(registry.RegistryArn))
+	}
+	if deploymentConfig := s.DeploymentConfiguration; deploymentConfig != nil {
+		value := fmt.Sprintf("min %d%%, max %d%%", aws.ToInt32(deploymentConfig.MinimumHealthyPercent), aws.ToInt32(deploymentConfig.MaximumPercent))
+		if breaker := deploymentConfig.DeploymentCircuitBreaker; breaker != nil && breaker.Enable {
+			value += fmt.Sprintf(", circuit breaker (rollback %t)", breaker.Rollback)
+		}
+		field("Deployment config", value)
+	}
+	field("Created", formatTimestamp(s.CreatedAt))
+	field("Tags", formatTags(s.Tags))
+	w.Flush()
+
+	fmt.Println("\nDeployments:")
+	printDeployments(os.Stdout, s.Deployments)
+
+	return nil
+}
+
+// printDeployments prints a table of service deployments.
+func printDeployments(out io.Writer, deployments []types.Deployment) {
+	dw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(dw, "  ID\tSTATUS\tROLLOUT\tTASK DEFINITION\tDESIRED\tRUNNING\tPENDING\tFAILED\tUPDATED")
+	for _, d := range deployments {
+		fmt.Fprintf(dw, "  %s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			aws.ToString(d.Id), aws.ToString(d.Status), d.RolloutState, taskDefinitionName(aws.ToString(d.TaskDefinition)),
+			d.DesiredCount, d.RunningCount, d.PendingCount, d.FailedTasks, formatTimestamp(d.UpdatedAt))
+	}
+	dw.Flush()
+}
+
+// containerPorts returns the port bindings of a container, or the port
+// mappings of its definition when there are none (awsvpc).
+func containerPorts(c types.Container, taskDef *types.TaskDefinition, name string) string {
+	var ports []string
+	for _, binding := range c.NetworkBindings {
+		ports = append(ports, fmt.Sprintf("%d->%d/%s", aws.ToInt32(binding.HostPort), aws.ToInt32(binding.ContainerPort), binding.Protocol))
+	}
+	if len(ports) > 0 {
+		return strings.Join(ports, ", ")
+	}
+
+	for _, definition := range taskDef.ContainerDefinitions {
+		if aws.ToString(definition.Name) != name {
+			continue
+		}
+		for _, mapping := range definition.PortMappings {
+			protocol := mapping.Protocol
+			if protocol == "" {
+				protocol = types.TransportProtocolTcp
+			}
+			ports = append(ports, fmt.Sprintf("%d/%s", aws.ToInt32(mapping.ContainerPort), protocol))
+		}
+	}
+	return strings.Join(ports, ", ")
+}
+
+func formatTags(tags []types.Tag) string {
+	var pairs []string
+	for _, tag := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", aws.ToString(tag.Key), aws.ToString(tag.Value)))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var nonEmpty []string
+	for _, value := range values {
+		if value != "" {
+			nonEmpty = append(nonEmpty, value)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
+
+func prefixed(prefix, value string) string {
+	if value == "" {
+		return ""
+	}
+	return prefix + value
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+// Check results, from best to worst, and checkInfo for optional items
+const (
+	checkInfo = "INFO"
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// callerActions are the actions ecsy itself calls.
+var callerActions = []string{
+	"ecs:ListClusters",
+	"ecs:ListServices",
+	"ecs:DescribeServices",
+	"ecs:ListTasks",
+	"ecs:DescribeTasks",
+	"ecs:ExecuteCommand",
+	"ssm:StartSession",
+}
+
+// taskRoleActions are the actions the SSM agent in a task needs.
+var taskRoleActions = []string{
+	"ssmmessages:CreateControlChannel",
+	"ssmmessages:CreateDataChannel",
+	"ssmmessages:OpenControlChannel",
+	"ssmmessages:OpenDataChannel",
+}
+
+// doctor collects and prints check results.
+type doctor struct {
+	failures int
+	warnings int
+}
+
+func (d *doctor) report(status, name, detail, remedy string) {
+	switch status {
+	case checkFail:
+		d.failures++
+	case checkWarn:
+		d.warnings++
+	}
+	fmt.Printf("[%s] %s: %s\n", status, name, detail)
+	if remedy != "" && status != checkPass {
+		fmt.Printf("       -> %s\n", remedy)
+	}
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	d := &doctor{}
+
+	// Local tools are only needed for the AWS CLI fallback
+	d.checkTool("aws CLI", "aws", "--version",
+		"Install it only to use 'aws ecs execute-command' as a fallback: https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html")
+	d.checkTool("session-manager-plugin", "session-manager-plugin", "--version",
+		"Install it only to use 'aws ecs execute-command' as a fallback: https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html")
+
+	// Credentials
+	selectedProfile, err := selectProfile()
+	if err != nil {
+		return fmt.Errorf("failed to select profile: %w", err)
+	}
+	profile = selectedProfile
+
+	cfg, err := loadAWSConfig(ctx, selectedProfile)
+	if err != nil {
+		d.report(checkFail, "Credentials", err.Error(), "Check the profile in ~/.aws/config")
+		return d.finish()
+	}
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		d.report(checkFail, "Credentials", err.Error(),
+			fmt.Sprintf("Refresh the credentials of profile %s (e.g. aws sso login --profile %s)", selectedProfile, selectedProfile))
+		return d.finish()
+	}
+	d.report(checkPass, "Credentials", fmt.Sprintf("%s (account %s)", aws.ToString(identity.Arn), aws.ToString(identity.Account)), "")
+
+	// Cluster and service, with MFA when required
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		d.report(checkFail, "Cluster access", err.Error(), "Allow ecs:ListClusters for this identity")
+		return d.finish()
+	}
+	iamClient := iam.NewFromConfig(cfg)
+
+	// IAM permissions of the caller, against the resources exec uses
+	checkCaller := func(resourceArns []string) {
+		if sourceArn, ok := simulationSourceArn(aws.ToString(identity.Arn)); ok {
+			d.checkPermissions(ctx, iamClient, "Caller IAM permissions", sourceArn, callerActions, resourceArns,
+				"Grant the denied actions to "+sourceArn)
+		} else {
+			d.report(checkWarn, "Caller IAM permissions", "cannot simulate policies for "+aws.ToString(identity.Arn), "")
+		}
+	}
+
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+	describeOutput, err := ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(selectedCluster),
+		Services: []string{selectedService},
+	})
+	if err != nil || len(describeOutput.Services) == 0 {
+		d.report(checkFail, "Service", fmt.Sprintf("failed to describe service %s: %v", selectedService, err), "Allow ecs:DescribeServices")
+		checkCaller(nil)
+		return d.finish()
+	}
+	service := describeOutput.Services[0]
+
+	// Tasks of the service, or only --task when given
+	var tasks []types.Task
+	var tasksErr error
+	if task != "" {
+		tasks, tasksErr = describeTasks(ctx, ecsClient, selectedCluster, []string{task})
+	} else {
+		tasks, tasksErr = describeServiceTasks(ctx, ecsClient, selectedCluster, selectedService)
+	}
+
+	checkCaller(simulationResources(service, tasks))
+
+	// Service setting
+	if service.EnableExecuteCommand {
+		d.report(checkPass, "Service enableExecuteCommand", "enabled", "")
+	} else {
+		d.report(checkFail, "Service enableExecuteCommand", "disabled",
+			fmt.Sprintf("aws ecs update-service --cluster %s --service %s --enable-execute-command --force-new-deployment", selectedCluster, selectedService))
+	}
+
+	// Cluster configuration
+	logActions := d.checkClusterConfiguration(ctx, ecsClient, selectedCluster)
+
+	// Task role
+	d.checkTaskRole(ctx, ecsClient, iamClient, aws.ToString(service.TaskDefinition), logActions)
+
+	// Agents of running tasks
+	if tasksErr != nil {
+		d.report(checkWarn, "Exec agent", fmt.Sprintf("failed to describe tasks: %v", tasksErr), "Allow ecs:ListTasks and ecs:DescribeTasks")
+	} else {
+		d.checkAgents(selectedCluster, selectedService, tasks)
+	}
+
+	return d.finish()
+}
+
+// finish prints a summary and returns an error when any check failed.
+func (d *doctor) finish() error {
+	fmt.Printf("\n%d failure(s), %d warning(s)\n", d.failures, d.warnings)
+	if d.failures > 0 {
+		return fmt.Errorf("%d check(s) failed", d.failures)
+	}
+	return nil
+}
+
+// checkTool reports whether an optional local tool is installed and its
+// version. ecsy does not need the tool, so a missing one is not a warning.
+func (d *doctor) checkTool(name, binary, versionArg, remedy string) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		d.report(checkInfo, name, "not found (not needed by ecsy)", remedy)
+		return
+	}
+	output, err := exec.Command(path, versionArg).CombinedOutput()
+	version := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+	if err != nil || version == "" {
+		d.report(checkInfo, name, fmt.Sprintf("found at %s but failed to get its version (not needed by ecsy)", path), remedy)
+		return
+	}
+	d.report(checkPass, name, version, "")
+}
+
+// simulationSourceArn returns the IAM user or role ARN to simulate policies
+// for. Assumed role sessions are mapped to their role; role paths are not
+// part of the session ARN, so roles with a path cannot be resolved.
+func simulationSourceArn(callerArn string) (string, bool) {
+	parts := strings.SplitN(callerArn, ":", 6)
+	if len(parts) != 6 {
+		return "", false
+	}
+	partition, account, resource := parts[1], parts[4], parts[5]
+
+	switch {
+	case parts[2] == "iam" && strings.HasPrefix(resource, "user/"):
+		return callerArn, true
+	case parts[2] == "sts" && strings.HasPrefix(resource, "assumed-role/"):
+		roleName := strings.Split(strings.TrimPrefix(resource, "assumed-role/"), "/")[0]
+		return fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, account, roleName), true
+	}
+	return "", false
+}
+
+// simulationResources returns the ARNs to simulate the caller's actions
+// against: the cluster, the service and the first running task, which stands
+// for the others as policies rarely name single tasks.
+func simulationResources(service types.Service, tasks []types.Task) []string {
+	var arns []string
+	for _, arn := range []*string{service.ClusterArn, service.ServiceArn} {
+		if aws.ToString(arn) != "" {
+			arns = append(arns, aws.ToString(arn))
+		}
+	}
+	for _, t := range tasks {
+		if aws.ToString(t.LastStatus) == "RUNNING" {
+			arns = append(arns, aws.ToString(t.TaskArn))
+			break
+		}
+	}
+	return arns
+}
+
+// checkPermissions simulates actions for an IAM principal against the given
+// resources. Only explicit denies fail; an implicit deny may come from
+// conditions (MFA, source IP, tags) that the simulation has no context for.
+func (d *doctor) checkPermissions(ctx context.Context, client *iam.Client, name, sourceArn string, actions, resourceArns []string, remedy string) {
+	output, err := client.SimulatePrincipalPolicy(ctx, &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(sourceArn),
+		ActionNames:     actions,
+		ResourceArns:    resourceArns,
+	})
+	if err != nil {
+		d.report(checkWarn, name, fmt.Sprintf("could not simulate policies: %v", err),
+			"Allow iam:SimulatePrincipalPolicy to check permissions, or verify them manually")
+		return
+	}
+
+	var denied, notAllowed []string
+	for _, result := range output.EvaluationResults {
+		action := aws.ToString(result.EvalActionName)
+		switch simulationDecision(result) {
+		case iamtypes.PolicyEvaluationDecisionTypeExplicitDeny:
+			denied = append(denied, action)
+		case iamtypes.PolicyEvaluationDecisionTypeImplicitDeny:
+			if keys := missingContextKeys(result); len(keys) > 0 {
+				action += " (conditions on " + strings.Join(keys, ", ") + ")"
+			}
+			notAllowed = append(notAllowed, action)
+		}
+	}
+	if len(denied) > 0 {
+		d.report(checkFail, name, "explicitly denied: "+strings.Join(denied, ", "), remedy)
+	}
+	if len(notAllowed) > 0 {
+		d.report(checkWarn, name, "not allowed by the simulation: "+strings.Join(notAllowed, ", "),
+			remedy+", or verify conditions manually")
+	}
+	if len(denied) == 0 && len(notAllowed) == 0 {
+		d.report(checkPass, name, fmt.Sprintf("%d action(s) allowed", len(actions)), "")
+	}
+}
+
+// simulationDecision combines the decisions for each simulated resource: an
+// explicit deny of any resource wins, and an action allowed on any resource
+// is allowed, as not every action applies to every resource type.
+func simulationDecision(result iamtypes.EvaluationResult) iamtypes.PolicyEvaluationDecisionType {
+	decision := result.EvalDecision
+	for _, r := range result.ResourceSpecificResults {
+		switch {
+		case r.EvalResourceDecision == iamtypes.PolicyEvaluationDecisionTypeExplicitDeny:
+			return r.EvalResourceDecision
+		case r.EvalResourceDecision == iamtypes.PolicyEvaluationDecisionTypeAllowed:
+			decision = r.EvalResourceDecision
+		}
+	}
+	return decision
+}
+
+// missingContextKeys returns the condition keys a simulation had no values
+// for.
+func missingContextKeys(result iamtypes.EvaluationResult) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(values []string) {
+		for _, key := range values {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	add(result.MissingContextValues)
+	for _, r := range result.ResourceSpecificResults {
+		add(r.MissingContextValues)
+	}
+	return keys
+}
+
+// checkClusterConfiguration reports the cluster's execute command settings
+// and returns the extra actions the task role needs for session logging.
+func (d *doctor) checkClusterConfiguration(ctx context.Context, client *ecs.Client, clusterName string) []string {
+	output, err := client.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{clusterName},
+		Include:  []types.ClusterField{types.ClusterFieldConfigurations},
+	})
+	if err != nil || len(output.Clusters) == 0 {
+		d.report(checkWarn, "Cluster exec configuration", fmt.Sprintf("failed to describe cluster: %v", err), "Allow ecs:DescribeClusters")
+		return nil
+	}
+
+	cluster := output.Clusters[0]
+	if cluster.Configuration == nil || cluster.Configuration.ExecuteCommandConfiguration == nil {
+		d.report(checkPass, "Cluster exec configuration", "default (logging to the task's awslogs configuration, no KMS key)", "")
+		return nil
+	}
+	execConfig := cluster.Configuration.ExecuteCommandConfiguration
+
+	if execConfig.KmsKeyId != nil {
+		d.report(checkFail, "Cluster exec KMS key", aws.ToString(execConfig.KmsKeyId)+" (ecsy does not support KMS encrypted sessions)",
+			"Use 'aws ecs execute-command' with session-manager-plugin for this cluster, or remove the KMS key from the cluster configuration")
+	} else {
+		d.report(checkPass, "Cluster exec KMS key", "not configured", "")
+	}
+
+	logging := execConfig.Logging
+	if logging == "" {
+		logging = types.ExecuteCommandLoggingDefault
+	}
+	var logActions []string
+	detail := string(logging)
+	if logging == types.ExecuteCommandLoggingOverride && execConfig.LogConfiguration != nil {
+		logConfig := execConfig.LogConfiguration
+		var destinations []string
+		if logConfig.CloudWatchLogGroupName != nil {
+			destinations = append(destinations, "CloudWatch Logs "+aws.ToString(logConfig.CloudWatchLogGroupName))
+			logActions = append(logActions, "logs:CreateLogStream", "logs:DescribeLogStreams", "logs:PutLogEvents", "logs:DescribeLogGroups")
+		}
+		if logConfig.S3BucketName != nil {
+			destinations = append(destinations, "S3 "+aws.ToString(logConfig.S3BucketName))
+			logActions = append(logActions, "s3:PutObject", "s3:GetEncryptionConfiguration")
+		}
+		if len(destinations) > 0 {
+			detail += " (" + strings.Join(destinations, ", ") + ")"
+		}
+	}
+	d.report(checkPass, "Cluster exec logging", detail, "")
+	return logActions
+}
+
+// checkTaskRole checks that the task role of a task definition allows the
+// SSM agent to open sessions, and to write session logs when configured.
+func (d *doctor) checkTaskRole(ctx context.Context, client *ecs.Client, iamClient *iam.Client, taskDefinition string, logActions []string) {
+	output, err := client.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinition),
+	})
+	if err != nil {
+		d.report(checkWarn, "Task role", fmt.Sprintf("failed to describe task definition: %v", err), "Allow ecs:DescribeTaskDefinition")
+		return
+	}
+
+	roleArn := aws.ToString(output.TaskDefinition.TaskRoleArn)
+	if roleArn == "" {
+		d.report(checkFail, "Task role", "task definition "+taskDefinition+" has no task role",
+			"Add a taskRoleArn with the ssmmessages permissions to the task definition")
+		return
+	}
+	d.checkPermissions(ctx, iamClient, "Task role permissions", roleArn, append(append([]string{}, taskRoleActions...), logActions...), nil,
+		"Grant the denied actions to "+roleArn)
+}
+
+// checkAgents reports the ExecuteCommandAgent status of each container in
+// the running tasks.
+func (d *doctor) checkAgents(clusterName, serviceName string, tasks []types.Task) {
+	checked := 0
+	for _, t := range tasks {
+		if aws.ToString(t.LastStatus) != "RUNNING" {
+			continue
+		}
+		checked++
+		taskID := taskIDFromArn(aws.ToString(t.TaskArn))
+		if !t.EnableExecuteCommand {
+			d.report(checkFail, "Exec agent "+taskID, "task was started without execute command enabled",
+				fmt.Sprintf("Replace the task: aws ecs update-service --cluster %s --service %s --force-new-deployment", clusterName, serviceName))
+			continue
+		}
+
+		for _, c := range t.Containers {
+			status, reason := "not reported", ""
+			for _, agent := range c.ManagedAgents {
+				if agent.Name == types.ManagedAgentNameExecuteCommandAgent {
+					status, reason = aws.ToString(agent.LastStatus), aws.ToString(agent.Reason)
+				}
+			}
+
+			name := fmt.Sprintf("Exec agent %s/%s", taskID, aws.ToString(c.Name))
+			switch status {
+			case "RUNNING":
+				d.report(checkPass, name, status, "")
+			case "PENDING":
+				d.report(checkWarn, name, status, "Wait for the agent to start and run doctor again")
+			default:
+				if reason != "" {
+					status += ": " + reason
+				}
+				d.report(checkFail, name, status,
+					"Check the task role permissions above and that the task can reach the ssmmessages endpoint (NAT gateway or VPC endpoint)")
+			}
+		}
+	}
+
+	if checked == 0 {
+		d.report(checkWarn, "Exec agent", "no running tasks to check", "Start a task and run doctor again")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+func TestSimulationDecision(t *testing.T) {
+	resource := func(decision iamtypes.PolicyEvaluationDecisionType) iamtypes.ResourceSpecificResult {
+		return iamtypes.ResourceSpecificResult{EvalResourceName: aws.String("arn"), EvalResourceDecision: decision}
+	}
+	const (
+		allowed      = iamtypes.PolicyEvaluationDecisionTypeAllowed
+		implicitDeny = iamtypes.PolicyEvaluationDecisionTypeImplicitDeny
+		explicitDeny = iamtypes.PolicyEvaluationDecisionTypeExplicitDeny
+	)
+
+	tests := []struct {
+		name   string
+		result iamtypes.EvaluationResult
+		want   iamtypes.PolicyEvaluationDecisionType
+	}{
+		{"allowed without resources", iamtypes.EvaluationResult{EvalDecision: allowed}, allowed},
+		{"implicit deny without resources", iamtypes.EvaluationResult{EvalDecision: implicitDeny}, implicitDeny},
+		{"allowed on one resource", iamtypes.EvaluationResult{EvalDecision: implicitDeny, ResourceSpecificResults: []iamtypes.ResourceSpecificResult{
+			resource(implicitDeny), resource(allowed),
+		}}, allowed},
+		{"explicit deny on one resource", iamtypes.EvaluationResult{EvalDecision: explicitDeny, ResourceSpecificResults: []iamtypes.ResourceSpecificResult{
+			resource(allowed), resource(explicitDeny),
+		}}, explicitDeny},
+		{"implicit deny on every resource", iamtypes.EvaluationResult{EvalDecision: implicitDeny, ResourceSpecificResults: []iamtypes.ResourceSpecificResult{
+			resource(implicitDeny), resource(implicitDeny),
+		}}, implicitDeny},
+	}
+	for _, tt := range tests {
+		if got := simulationDecision(tt.result); got != tt.want {
+			t.Errorf("%s: simulationDecision() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMissingContextKeys(t *testing.T) {
+	result := iamtypes.EvaluationResult{
+		MissingContextValues: []string{"aws:MultiFactorAuthPresent"},
+		ResourceSpecificResults: []iamtypes.ResourceSpecificResult{
+			{MissingContextValues: []string{"aws:SourceIp", "aws:MultiFactorAuthPresent"}},
+			{MissingContextValues: []string{"ecs:cluster"}},
+		},
+	}
+	want := []string{"aws:MultiFactorAuthPresent", "aws:SourceIp", "ecs:cluster"}
+	if got := missingContextKeys(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("missingContextKeys() = %q, want %q", got, want)
+	}
+	if got := missingContextKeys(iamtypes.EvaluationResult{}); got != nil {
+		t.Errorf("missingContextKeys() without conditions = %q, want nil", got)
+	}
+}
+
+func TestSimulationResources(t *testing.T) {
+	service := types.Service{
+		ClusterArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/my-cluster"),
+		ServiceArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:service/my-cluster/web"),
+	}
+	tasks := []types.Task{
+		testTask("a", "PENDING"),
+		testTask("b", "RUNNING"),
+		testTask("c", "RUNNING"),
+	}
+
+	want := []string{
+		"arn:aws:ecs:ap-northeast-1:123456789012:cluster/my-cluster",
+		"arn:aws:ecs:ap-northeast-1:123456789012:service/my-cluster/web",
+		"arn:aws:ecs:ap-northeast-1:123456789012:task/my-cluster/b",
+	}
+	if got := simulationResources(service, tasks); !reflect.DeepEqual(got, want) {
+		t.Errorf("simulationResources() = %q, want %q", got, want)
+	}
+	if got := simulationResources(service, nil); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("simulationResources() without tasks = %q, want %q", got, want[:2])
+	}
+}
+
+func TestSimulationSourceArn(t *testing.T) {
+	tests := []struct {
+		callerArn string
+		want      string
+		ok        bool
+	}{
+		{"arn:aws:iam::123456789012:user/alice", "arn:aws:iam::123456789012:user/alice", true},
+		{"arn:aws:sts::123456789012:assumed-role/Developer/alice@example.com", "arn:aws:iam::123456789012:role/Developer", true},
+		{"arn:aws-cn:sts::123456789012:assumed-role/Ops/session", "arn:aws-cn:iam::123456789012:role/Ops", true},
+		{"arn:aws:sts::123456789012:federated-user/bob", "", false},
+		{"arn:aws:iam::123456789012:root", "", false},
+		{"not an arn", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := simulationSourceArn(tt.callerArn)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("simulationSourceArn(%q) = %q, %t, want %q, %t", tt.callerArn, got, ok, tt.want, tt.ok)
+		}
+	}
+}
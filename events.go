@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+func runEvents(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Select profile and cluster
+	_, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Select service
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	return printServiceEvents(ctx, os.Stdout, ecsClient, selectedCluster, selectedService, eventsLimit, eventsStopped)
+}
+
+// printServiceEvents prints the latest events of a service and why its
+// most recently stopped tasks stopped.
+func printServiceEvents(ctx context.Context, w io.Writer, client *ecs.Client, clusterName, serviceName string, eventLimit, stoppedLimit int) error {
+	describeOutput, err := client.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(clusterName),
+		Services: []string{serviceName},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	if len(describeOutput.Services) == 0 {
+		return fmt.Errorf("service not found: %s", serviceName)
+	}
+	service := describeOutput.Services[0]
+
+	fmt.Fprintf(w, "Service %s: %d running, %d pending, %d desired\n",
+		serviceName, service.RunningCount, service.PendingCount, service.DesiredCount)
+
+	// Events are returned newest first
+	fmt.Fprintln(w, "\nRecent events:")
+	if len(service.Events) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for i, event := range service.Events {
+		if i >= eventLimit {
+			break
+		}
+		fmt.Fprintf(w, "  %s  %s\n", formatEventTime(event.CreatedAt), aws.ToString(event.Message))
+	}
+
+	stoppedTasks, err := listStoppedTasks(ctx, client, clusterName, serviceName)
+	if err != nil {
+		return fmt.Errorf("failed to list stopped tasks: %w", err)
+	}
+
+	fmt.Fprintln(w, "\nRecently stopped tasks:")
+	if len(stoppedTasks) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for i, t := range stoppedTasks {
+		if i >= stoppedLimit {
+			break
+		}
+		fmt.Fprintf(w, "  %s  %s  %s: %s\n", formatEventTime(t.StoppedAt), taskIDFromArn(aws.ToString(t.TaskArn)),
+			t.StopCode, aws.ToString(t.StoppedReason))
+		for _, c := range t.Containers {
+			exitCode := "-"
+			if c.ExitCode != nil {
+				exitCode = fmt.Sprintf("%d", *c.ExitCode)
+			}
+			line := fmt.Sprintf("      %s: exit code %s", aws.ToString(c.Name), exitCode)
+			if c.Reason != nil {
+				line += ", " + aws.ToString(c.Reason)
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	return nil
+}
+
+// listStoppedTasks returns the stopped tasks ECS still remembers for a
+// service, most recently stopped first.
+func listStoppedTasks(ctx context.Context, client *ecs.Client, clusterName, serviceName string) ([]types.Task, error) {
+	var taskArns []string
+	var nextToken *string
+
+	for {
+		listOutput, err := client.ListTasks(ctx, &ecs.ListTasksInput{
+			Cluster:       aws.String(clusterName),
+			ServiceName:   aws.String(serviceName),
+			DesiredStatus: types.DesiredStatusStopped,
+			NextToken:     nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		taskArns = append(taskArns, listOutput.TaskArns...)
+
+		if listOutput.NextToken == nil {
+			break
+		}
+		nextToken = listOutput.NextToken
+	}
+
+	tasks, err := describeTasks(ctx, client, clusterName, taskArns)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return aws.ToTime(tasks[i].StoppedAt).After(aws.ToTime(tasks[j].StoppedAt))
+	})
+	return tasks, nil
+}
+
+func formatEventTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/spf13/cobra"
+)
+
+// ECS exec sessions always run on a pseudo terminal, so stdout and stderr
+// arrive merged. Remote commands are wrapped in a small sh script that
+// disables echo, marks stderr lines and reports the exit status using
+// control lines starting with markerByte and a nonce chosen per invocation,
+// so that command output can't be mistaken for them.
+const (
+	markerByte   = '\036'
+	markerReady  = 'R'
+	markerStderr = 'E'
+	markerExit   = 'X'
+)
+
+// remoteWrapper returns the sh script that runs script for runRemoteCommand.
+// The command's stdout goes through cat so that it's a pipe rather than the
+// terminal, and its stdin is /dev/null unless input is going to be sent.
+func remoteWrapper(script, nonce string, stdin bool) string {
+	input := " </dev/null"
+	if stdin {
+		input = ""
+	}
+	marker := `\036` + nonce
+	return `stty -echo -onlcr 2>/dev/null; printf '` + marker + `R\n'; exec 3>&1; ` +
+		`{ { ( ` + script + ` )` + input + ` 2>&4 4>&- 3>&-; printf '` + marker + `X%d\n' "$?" >&3; } | cat >&3; } 4>&1 | ` +
+		`while IFS= read -r l || [ -n "$l" ]; do printf '` + marker + `E%s\n' "$l"; done`
+}
+
+// newMarkerNonce returns a random nonce for the control lines of one remote
+// command.
+func newMarkerNonce() string {
+	u := newUUID()
+	return hex.EncodeToString(u[:8])
+}
+
+// remoteExitError is returned when a remote command exits with a non-zero
+// status. main uses it as the exit code of ecsy itself.
+type remoteExitError struct {
+	code int
+}
+
+func (e *remoteExitError) Error() string {
+	return fmt.Sprintf("remote command exited with status %d", e.code)
+}
+
+// errNoExitStatus is returned when the wrapper never reported an exit
+// status, which usually means the container has no /bin/sh.
+var errNoExitStatus = errors.New("remote command did not report an exit status")
+
+func runExec(cmd *cobra.Command, args []string) error {
+	// Check the staged execution flags before any selection
+	if execOnFailure != onFailureStop && execOnFailure != onFailureContinue {
+		return fmt.Errorf("invalid --on-failure %q (want %s or %s)", execOnFailure, onFailureStop, onFailureContinue)
+	}
+	if execBatchSize <= 0 {
+		return fmt.Errorf("--batch-size must be positive")
+	}
+
+	ctx := context.Background()
+
+	// Select profile and cluster
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Select service
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	script := shellJoin(args)
+
+	// Fan out over several tasks
+	if execAll || len(execTasks) > 0 || execStaged {
+		return runExecFanout(ctx, cfg, ecsClient, selectedCluster, selectedService, script)
+	}
+
+	// Select task
+	selectedTask, err := selectTask(ctx, cfg, ecsClient, selectedCluster, selectedService)
+	if err != nil {
+		return fmt.Errorf("failed to select task: %w", err)
+	}
+
+	// Select container if not specified
+	selectedContainer := container
+	if selectedContainer == "" {
+		selectedContainer, err = selectContainer(ctx, ecsClient, selectedCluster, selectedTask)
+		if err != nil {
+			return fmt.Errorf("failed to select container: %w", err)
+		}
+	}
+
+	exitCode, err := runRemoteCommand(ctx, cfg, selectedCluster, selectedTask, selectedContainer, script, nil, os.Stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return &remoteExitError{code: exitCode}
+	}
+	return nil
+}
+
+func runExecFanout(ctx context.Context, cfg aws.Config, ecsClient *ecs.Client, clusterName, serviceName, script string) error {
+	taskIDs, err := selectFanoutTasks(ctx, ecsClient, clusterName, serviceName, execTasks)
+	if err != nil {
+		return fmt.Errorf("failed to select tasks: %w", err)
+	}
+
+	// All tasks of a service share a task definition, so pick the container once
+	selectedContainer := container
+	if selectedContainer == "" {
+		selectedContainer, err = selectContainer(ctx, ecsClient, clusterName, taskIDs[0])
+		if err != nil {
+			return fmt.Errorf("failed to select container: %w", err)
+		}
+	}
+
+	var results []fanoutResult
+	if execStaged {
+		run := func(ids []string, maxParallel int) []fanoutResult {
+			return runFanout(ctx, cfg, clusterName, ids, selectedContainer, script, maxParallel)
+		}
+		results = runStagedFanout(taskIDs, execBatchSize, execOnFailure, run, confirmFanout)
+	} else {
+		fmt.Fprintf(os.Stderr, "Running on %d task(s) of service %s...\n", len(taskIDs), serviceName)
+		results = runFanout(ctx, cfg, clusterName, taskIDs, selectedContainer, script, execMaxParallel)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	printFanoutSummary(os.Stderr, results)
+
+	for _, result := range results {
+		if result.failed() {
+			return &remoteExitError{code: 1}
+		}
+	}
+	return nil
+}
+
+// runRemoteCommand runs script with sh in the container and returns its exit
+// status. stdin, if not nil, is fed to the script line by line and must be
+// line oriented text (e.g. base64); it is terminated with EOF.
+func runRemoteCommand(ctx context.Context, cfg aws.Config, clusterName, taskID, containerName, script string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	ecsClient := ecs.NewFromConfig(cfg)
+	nonce := newMarkerNonce()
+	output, err := ecsClient.ExecuteCommand(ctx, &ecs.ExecuteCommandInput{
+		Cluster:     aws.String(clusterName),
+		Task:        aws.String(taskID),
+		Container:   aws.String(containerName),
+		Command:     aws.String("sh -c " + shellQuote(remoteWrapper(script, nonce, stdin != nil))),
+		Interactive: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute command: %w", err)
+	}
+	if output.Session == nil || output.Session.StreamUrl == nil || output.Session.TokenValue == nil {
+		return 0, fmt.Errorf("no session returned by ExecuteCommand")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	demux := newRemoteOutput(stdout, stderr, nonce)
+	dc, err := openDataChannel(ctx, aws.ToString(output.Session.StreamUrl), aws.ToString(output.Session.TokenValue), demux, stderr)
+	if err != nil {
+		return 0, err
+	}
+	defer dc.Close()
+
+	// Feed stdin once the wrapper has disabled echo
+	if stdin != nil {
+		go func() {
+			select {
+			case <-demux.ready:
+			case <-dc.Done():
+				return
+			}
+			sendRemoteInput(dc, stdin)
+		}()
+	}
+
+	if err := dc.Wait(); err != nil {
+		return 0, err
+	}
+	demux.Flush()
+
+	if !demux.hasExitCode {
+		if message := strings.TrimSpace(dc.CloseMessage()); message != "" {
+			return 0, fmt.Errorf("%w: %s", errNoExitStatus, message)
+		}
+		return 0, fmt.Errorf("%w (is /bin/sh available in the container?)", errNoExitStatus)
+	}
+	return demux.exitCode, nil
+}
+
+// sendRemoteInput sends r to the remote terminal followed by EOF.
+func sendRemoteInput(dc *dataChannel, r io.Reader) {
+	buf := make([]byte, streamChunkSize)
+	atLineStart := true
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			data := make([]byte, n)
+			copy(data, buf[:n])
+			if dc.sendInput(payloadOutput, data) != nil {
+				return
+			}
+			atLineStart = data[n-1] == '\n'
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// A terminal only treats ^D as EOF at the start of a line
+	eof := []byte{4}
+	if !atLineStart {
+		eof = []byte{4, 4}
+	}
+	dc.sendInput(payloadOutput, eof)
+}
+
+// remoteOutput splits the wrapper's output back into stdout, stderr and the
+// exit status.
+type remoteOutput struct {
+	stdout io.Writer
+	stderr io.Writer
+	marker []byte
+
+	buf         []byte
+	crlf        bool
+	ready       chan struct{}
+	readyClosed bool
+	exitCode    int
+	hasExitCode bool
+}
+
+func newRemoteOutput(stdout, stderr io.Writer, nonce string) *remoteOutput {
+	return &remoteOutput{
+		stdout: stdout,
+		stderr: stderr,
+		marker: []byte(string(markerByte) + nonce),
+		ready:  make(chan struct{}),
+	}
+}
+
+func (o *remoteOutput) Write(p []byte) (int, error) {
+	o.buf = append(o.buf, p...)
+
+	for {
+		i := bytes.IndexByte(o.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := o.buf[:i+1]
+		o.buf = o.buf[i+1:]
+		if err := o.handleLine(line); err != nil {
+			return 0, err
+		}
+	}
+
+	// Pass through partial stdout lines, keeping a possible control line
+	if i := bytes.IndexByte(o.buf, markerByte); i != 0 {
+		if i < 0 {
+			i = len(o.buf)
+			if o.crlf && bytes.HasSuffix(o.buf, []byte("\r")) {
+				i--
+			}
+		}
+		if _, err := o.stdout.Write(o.buf[:i]); err != nil {
+			return 0, err
+		}
+		o.buf = o.buf[i:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any remaining buffered output.
+func (o *remoteOutput) Flush() {
+	if len(o.buf) > 0 {
+		o.handleLine(o.buf)
+		o.buf = nil
+	}
+}
+
+func (o *remoteOutput) handleLine(line []byte) error {
+	// stty may be missing, in which case the terminal still sends CRLF
+	if o.crlf && bytes.HasSuffix(line, []byte("\r\n")) {
+		line = append(line[:len(line)-2], '\n')
+	}
+
+	i := bytes.Index(line, o.marker)
+	if i < 0 {
+		_, err := o.stdout.Write(line)
+		return err
+	}
+	if i > 0 {
+		if _, err := o.stdout.Write(line[:i]); err != nil {
+			return err
+		}
+		line = line[i:]
+	}
+
+	body := strings.TrimRight(string(line[len(o.marker):]), "\r\n")
+	if body == "" {
+		return nil
+	}
+	switch body[0] {
+	case markerReady:
+		if !o.readyClosed {
+			o.crlf = strings.HasSuffix(string(line), "\r\n")
+			o.readyClosed = true
+			close(o.ready)
+		}
+	case markerStderr:
+		_, err := fmt.Fprintln(o.stderr, body[1:])
+		return err
+	case markerExit:
+		if code, err := strconv.Atoi(body[1:]); err == nil {
+			o.exitCode = code
+			o.hasExitCode = true
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes s for use as a single sh word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellJoin quotes args into a sh command line.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const testNonce = "0123456789abcdef"
+
+func TestRemoteOutput(t *testing.T) {
+	m := "\036" + testNonce
+	tests := []struct {
+		name       string
+		chunks     []string
+		wantStdout string
+		wantStderr string
+		wantExit   int
+		hasExit    bool
+		ready      bool
+	}{
+		{
+			name:       "stdout, stderr and exit status",
+			chunks:     []string{m + "R\n", "out 1\n", m + "Eerr 1\n", "out 2\n", m + "X3\n"},
+			wantStdout: "out 1\nout 2\n",
+			wantStderr: "err 1\n",
+			wantExit:   3,
+			hasExit:    true,
+			ready:      true,
+		},
+		{
+			name:       "control lines split across writes",
+			chunks:     []string{m[:5], m[5:] + "R", "\nou", "t\n" + m + "E", "err\n" + m + "X", "0\n"},
+			wantStdout: "out\n",
+			wantStderr: "err\n",
+			hasExit:    true,
+			ready:      true,
+		},
+		{
+			name:       "partial stdout line before the exit status",
+			chunks:     []string{m + "R\n", "no newline", m + "X0\n"},
+			wantStdout: "no newline",
+			hasExit:    true,
+			ready:      true,
+		},
+		{
+			name:       "CRLF when stty is missing",
+			chunks:     []string{m + "R\r\n", "out\r\n", m + "Eerr\r\n", m + "X1\r\n"},
+			wantStdout: "out\n",
+			wantStderr: "err\n",
+			wantExit:   1,
+			hasExit:    true,
+			ready:      true,
+		},
+		{
+			name:       "output that looks like a control line without the nonce",
+			chunks:     []string{m + "R\n", "\036X9\n", "\036Efake\n", "\036" + "0000000000000000X7\n", m + "X0\n"},
+			wantStdout: "\036X9\n\036Efake\n\036" + "0000000000000000X7\n",
+			hasExit:    true,
+			ready:      true,
+		},
+		{
+			name:       "no exit status",
+			chunks:     []string{"sh: not found\n", "trailing"},
+			wantStdout: "sh: not found\ntrailing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			o := newRemoteOutput(&stdout, &stderr, testNonce)
+			for _, chunk := range tt.chunks {
+				if n, err := o.Write([]byte(chunk)); err != nil || n != len(chunk) {
+					t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+				}
+			}
+			o.Flush()
+
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", got, tt.wantStdout)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", got, tt.wantStderr)
+			}
+			if o.hasExitCode != tt.hasExit || o.exitCode != tt.wantExit {
+				t.Errorf("exit status = %d, %t, want %d, %t", o.exitCode, o.hasExitCode, tt.wantExit, tt.hasExit)
+			}
+			if o.readyClosed != tt.ready {
+				t.Errorf("ready = %t, want %t", o.readyClosed, tt.ready)
+			}
+		})
+	}
+}
+
+func TestRemoteWrapper(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+
+	tests := []struct {
+		name       string
+		script     string
+		stdin      string
+		wantStdout string
+		wantStderr string
+		wantExit   int
+	}{
+		{
+			name:       "stdout, stderr and exit status",
+			script:     "echo out; echo err >&2; exit 3",
+			wantStdout: "out\n",
+			wantStderr: "err\n",
+			wantExit:   3,
+		},
+		{
+			name:       "stdin is /dev/null without input",
+			script:     "cat; echo done",
+			stdin:      "ignored\n",
+			wantStdout: "done\n",
+		},
+		{
+			name:       "stdout is a pipe",
+			script:     "[ -p /dev/stdout ] && echo pipe",
+			wantStdout: "pipe\n",
+		},
+		{
+			name:       "output that looks like a control line",
+			script:     `printf '\036X9\n'`,
+			wantStdout: "\036X9\n",
+		},
+		{
+			name:       "quoted arguments",
+			script:     shellJoin([]string{"printf", "%s|", "a b", "it's"}),
+			wantStdout: "a b|it's|",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			o := newRemoteOutput(&stdout, &stderr, testNonce)
+			cmd := exec.Command(sh, "-c", remoteWrapper(tt.script, testNonce, false))
+			cmd.Stdin = strings.NewReader(tt.stdin)
+			cmd.Stdout = o
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("running the wrapper: %v", err)
+			}
+			o.Flush()
+
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", got, tt.wantStdout)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", got, tt.wantStderr)
+			}
+			if !o.hasExitCode || o.exitCode != tt.wantExit {
+				t.Errorf("exit status = %d, %t, want %d", o.exitCode, o.hasExitCode, tt.wantExit)
+			}
+		})
+	}
+
+	if !strings.Contains(remoteWrapper("cat", testNonce, true), "( cat ) 2>&4") {
+		t.Error("remoteWrapper() redirects stdin although input is sent")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "''"},
+		{"abc", "'abc'"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/manifoldco/promptui"
+)
+
+const shortTaskIDLength = 8
+
+// fanoutResult is the outcome of running a command on one task.
+type fanoutResult struct {
+	TaskID   string
+	ExitCode int
+	Duration time.Duration
+	Err      error
+	Skipped  bool
+}
+
+func (r fanoutResult) failed() bool {
+	return r.Err != nil || r.ExitCode != 0
+}
+
+// Failure policies for staged execution
+const (
+	onFailureStop     = "stop"
+	onFailureContinue = "continue"
+)
+
+// selectFanoutTasks returns the running tasks of a service to run a command
+// on: all of them, or those matching the IDs (or ID prefixes) in ids.
+func selectFanoutTasks(ctx context.Context, client *ecs.Client, clusterName, serviceName string, ids []string) ([]string, error) {
+	tasks, err := describeServiceTasks(ctx, client, clusterName, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []string
+	for _, task := range tasks {
+		if aws.ToString(task.LastStatus) == "RUNNING" {
+			running = append(running, taskIDFromArn(aws.ToString(task.TaskArn)))
+		}
+	}
+	if len(running) == 0 {
+		return nil, fmt.Errorf("no running tasks found for service %s", serviceName)
+	}
+	if len(ids) == 0 {
+		return running, nil
+	}
+	return matchTaskIDs(running, ids)
+}
+
+// matchTaskIDs returns the running tasks matching the IDs or ID prefixes in
+// ids, each once even when given repeatedly.
+func matchTaskIDs(running, ids []string) ([]string, error) {
+	var selected []string
+	for _, id := range ids {
+		var matches []string
+		for _, taskID := range running {
+			if strings.HasPrefix(taskID, id) {
+				matches = append(matches, taskID)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("no running task matches %s", id)
+		case 1:
+			selected = append(selected, matches[0])
+		default:
+			return nil, fmt.Errorf("task ID %s is ambiguous", id)
+		}
+	}
+	return uniqueTaskIDs(selected), nil
+}
+
+// runFanout runs script on every task with at most maxParallel sessions at
+// a time. Output lines are prefixed with the short task ID.
+func runFanout(ctx context.Context, cfg aws.Config, clusterName string, taskIDs []string, containerName, script string, maxParallel int) []fanoutResult {
+	if maxParallel <= 0 || maxParallel > len(taskIDs) {
+		maxParallel = len(taskIDs)
+	}
+
+	var outputMu sync.Mutex
+	results := make([]fanoutResult, len(taskIDs))
+	semaphore := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+
+	for i, taskID := range taskIDs {
+		wg.Add(1)
+		go func(i int, taskID string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			prefix := fmt.Sprintf("[%s] ", shortTaskID(taskID))
+			stdout := &prefixWriter{mu: &outputMu, w: os.Stdout, prefix: prefix}
+			stderr := &prefixWriter{mu: &outputMu, w: os.Stderr, prefix: prefix}
+
+			start := time.Now()
+			exitCode, err := runRemoteCommand(ctx, cfg, clusterName, taskID, containerName, script, nil, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+
+			results[i] = fanoutResult{
+				TaskID:   taskID,
+				ExitCode: exitCode,
+				Duration: time.Since(start),
+				Err:      err,
+			}
+		}(i, taskID)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// fanoutRunner runs the command on a set of tasks, like runFanout.
+type fanoutRunner func(taskIDs []string, maxParallel int) []fanoutResult
+
+// runStagedFanout runs the command on one canary task and, once confirm
+// agrees, on the remaining tasks in batches. With the stop policy no further
+// batches are started once a task has failed.
+func runStagedFanout(taskIDs []string, batchSize int, onFailure string, run fanoutRunner, confirm func(label string) bool) []fanoutResult {
+	skip := func(results []fanoutResult, remaining []string) []fanoutResult {
+		for _, taskID := range remaining {
+			results = append(results, fanoutResult{TaskID: taskID, Skipped: true})
+		}
+		return results
+	}
+
+	// Canary
+	fmt.Fprintf(os.Stderr, "Running on canary task %s...\n", taskIDs[0])
+	results := run(taskIDs[:1], 1)
+	canary := results[0]
+	if canary.Err != nil {
+		fmt.Fprintf(os.Stderr, "Canary failed: %v\n", canary.Err)
+	} else {
+		fmt.Fprintf(os.Stderr, "Canary exited with status %d in %s\n", canary.ExitCode, canary.Duration.Round(time.Millisecond))
+	}
+
+	remaining := taskIDs[1:]
+	if len(remaining) == 0 {
+		return results
+	}
+	if canary.failed() && onFailure == onFailureStop {
+		fmt.Fprintln(os.Stderr, "Stopping because the canary failed.")
+		return skip(results, remaining)
+	}
+
+	if !confirm(fmt.Sprintf("Continue with the remaining %d task(s) in batches of %d", len(remaining), batchSize)) {
+		fmt.Fprintln(os.Stderr, "Cancelled.")
+		return skip(results, remaining)
+	}
+
+	for len(remaining) > 0 {
+		size := batchSize
+		if size > len(remaining) {
+			size = len(remaining)
+		}
+		batch := remaining[:size]
+		remaining = remaining[size:]
+
+		fmt.Fprintf(os.Stderr, "Running on batch of %d task(s)...\n", len(batch))
+		batchResults := run(batch, size)
+		results = append(results, batchResults...)
+
+		if onFailure == onFailureStop {
+			for _, result := range batchResults {
+				if result.failed() {
+					fmt.Fprintf(os.Stderr, "Stopping because task %s failed.\n", result.TaskID)
+					return skip(results, remaining)
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+// confirmFanout asks whether to continue a staged execution.
+func confirmFanout(label string) bool {
+	prompt := promptui.Prompt{
+		Label:     label,
+		Stdout:    os.Stderr,
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// printFanoutSummary prints a table of per-task results.
+func printFanoutSummary(w io.Writer, results []fanoutResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tEXIT\tDURATION\tERROR")
+	for _, result := range results {
+		exitCode := fmt.Sprintf("%d", result.ExitCode)
+		errText := ""
+		if result.Err != nil {
+			exitCode = "-"
+			errText = result.Err.Error()
+		}
+		if result.Skipped {
+			exitCode = "-"
+			errText = "skipped"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.TaskID, exitCode, result.Duration.Round(time.Millisecond), errText)
+	}
+	tw.Flush()
+}
+
+func shortTaskID(taskID string) string {
+	if len(taskID) > shortTaskIDLength {
+		return taskID[:shortTaskIDLength]
+	}
+	return taskID
+}
+
+// prefixWriter writes complete lines to w with a prefix, serialising
+// writes from concurrent sessions through mu.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes a trailing partial line, if any.
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	line := append(p.buf, '\n')
+	p.buf = nil
+	return p.writeLine(line)
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
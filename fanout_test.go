@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"single line", []string{"hello\n"}, "[abc] hello\n"},
+		{"several lines in one write", []string{"a\nb\n"}, "[abc] a\n[abc] b\n"},
+		{"line split across writes", []string{"hel", "lo\nwor", "ld\n"}, "[abc] hello\n[abc] world\n"},
+		{"trailing partial line", []string{"a\nno newline"}, "[abc] a\n[abc] no newline\n"},
+		{"empty line", []string{"\n"}, "[abc] \n"},
+		{"nothing written", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := &prefixWriter{mu: &sync.Mutex{}, w: &out, prefix: "[abc] "}
+			for _, chunk := range tt.chunks {
+				if n, err := p.Write([]byte(chunk)); err != nil || n != len(chunk) {
+					t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+				}
+			}
+			if err := p.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixWriterConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	var out bytes.Buffer
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := &prefixWriter{mu: &mu, w: &out, prefix: fmt.Sprintf("[%d] ", i)}
+			for j := 0; j < 50; j++ {
+				// Write each line in two pieces to interleave partial lines
+				p.Write([]byte(fmt.Sprintf("line %d ", j)))
+				p.Write([]byte("end\n"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 200 {
+		t.Fatalf("got %d lines, want 200", len(lines))
+	}
+	for _, line := range lines {
+		var i, j int
+		if _, err := fmt.Sscanf(line, "[%d] line %d end", &i, &j); err != nil {
+			t.Errorf("garbled line %q", line)
+		}
+	}
+}
+
+func TestShortTaskID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0123456789abcdef", "01234567"},
+		{"01234567", "01234567"},
+		{"abc", "abc"},
+	}
+	for _, tt := range tests {
+		if got := shortTaskID(tt.in); got != tt.want {
+			t.Errorf("shortTaskID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPrintFanoutSummary(t *testing.T) {
+	var out bytes.Buffer
+	printFanoutSummary(&out, []fanoutResult{
+		{TaskID: "task-1", ExitCode: 0, Duration: 1500 * time.Millisecond},
+		{TaskID: "task-2", ExitCode: 2, Duration: time.Second},
+		{TaskID: "task-3", Err: fmt.Errorf("boom")},
+		{TaskID: "task-4", Skipped: true},
+	})
+
+	want := []string{
+		"TASK    EXIT  DURATION  ERROR",
+		"task-1  0     1.5s",
+		"task-2  2     1s",
+		"task-3  -     0s        boom",
+		"task-4  -     0s        skipped",
+	}
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("summary =\n%s", out.String())
+	}
+	for i := range want {
+		if strings.TrimRight(got[i], " ") != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRunStagedFanout(t *testing.T) {
+	taskIDs := []string{"t1", "t2", "t3", "t4", "t5"}
+
+	tests := []struct {
+		name        string
+		taskIDs     []string
+		batchSize   int
+		onFailure   string
+		failing     map[string]bool
+		confirm     bool
+		wantBatches [][]string
+		wantConfirm bool
+		wantSkipped []string
+	}{
+		{
+			name:        "canary then batches",
+			taskIDs:     taskIDs,
+			batchSize:   2,
+			onFailure:   onFailureStop,
+			confirm:     true,
+			wantBatches: [][]string{{"t1"}, {"t2", "t3"}, {"t4", "t5"}},
+			wantConfirm: true,
+		},
+		{
+			name:        "last batch is smaller",
+			taskIDs:     taskIDs,
+			batchSize:   3,
+			onFailure:   onFailureStop,
+			confirm:     true,
+			wantBatches: [][]string{{"t1"}, {"t2", "t3", "t4"}, {"t5"}},
+			wantConfirm: true,
+		},
+		{
+			name:        "stop after a failed canary",
+			taskIDs:     taskIDs,
+			batchSize:   2,
+			onFailure:   onFailureStop,
+			failing:     map[string]bool{"t1": true},
+			confirm:     true,
+			wantBatches: [][]string{{"t1"}},
+			wantSkipped: []string{"t2", "t3", "t4", "t5"},
+		},
+		{
+			name:        "continue after a failed canary",
+			taskIDs:     taskIDs,
+			batchSize:   2,
+			onFailure:   onFailureContinue,
+			failing:     map[string]bool{"t1": true},
+			confirm:     true,
+			wantBatches: [][]string{{"t1"}, {"t2", "t3"}, {"t4", "t5"}},
+			wantConfirm: true,
+		},
+		{
+			name:        "stop after a failed batch",
+			taskIDs:     taskIDs,
+			batchSize:   2,
+			onFailure:   onFailureStop,
+			failing:     map[string]bool{"t3": true},
+			confirm:     true,
+			wantBatches: [][]string{{"t1"}, {"t2", "t3"}},
+			wantConfirm: true,
+			wantSkipped: []string{"t4", "t5"},
+		},
+		{
+			name:        "continue after a failed batch",
+			taskIDs:     taskIDs,
+			batchSize:   2,
+			onFailure:   onFailureContinue,
+			failing:     map[string]bool{"t3": true},
+			confirm:     true,
+			wantBatches: [][]string{{"t1"}, {"t2", "t3"}, {"t4", "t5"}},
+			wantConfirm: true,
+		},
+		{
+			name:        "declined after the canary",
+			taskIDs:     taskIDs,
+			batchSize:   2,
+			onFailure:   onFailureStop,
+			wantBatches: [][]string{{"t1"}},
+			wantConfirm: true,
+			wantSkipped: []string{"t2", "t3", "t4", "t5"},
+		},
+		{
+			name:        "only the canary",
+			taskIDs:     []string{"t1"},
+			batchSize:   2,
+			onFailure:   onFailureStop,
+			wantBatches: [][]string{{"t1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches [][]string
+			run := func(ids []string, maxParallel int) []fanoutResult {
+				if maxParallel != len(ids) {
+					t.Errorf("batch %v run with %d in parallel", ids, maxParallel)
+				}
+				batches = append(batches, append([]string(nil), ids...))
+				results := make([]fanoutResult, len(ids))
+				for i, id := range ids {
+					results[i] = fanoutResult{TaskID: id}
+					if tt.failing[id] {
+						results[i].ExitCode = 1
+					}
+				}
+				return results
+			}
+			confirmed := false
+			confirm := func(label string) bool {
+				confirmed = true
+				return tt.confirm
+			}
+
+			results := runStagedFanout(tt.taskIDs, tt.batchSize, tt.onFailure, run, confirm)
+
+			if !reflect.DeepEqual(batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", batches, tt.wantBatches)
+			}
+			if confirmed != tt.wantConfirm {
+				t.Errorf("asked for confirmation = %t, want %t", confirmed, tt.wantConfirm)
+			}
+			var order, skipped []string
+			for _, result := range results {
+				order = append(order, result.TaskID)
+				if result.Skipped {
+					skipped = append(skipped, result.TaskID)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.taskIDs) {
+				t.Errorf("results for %v, want %v", order, tt.taskIDs)
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("skipped %v, want %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestRunExecValidatesFlags(t *testing.T) {
+	savedOnFailure, savedBatchSize := execOnFailure, execBatchSize
+	t.Cleanup(func() { execOnFailure, execBatchSize = savedOnFailure, savedBatchSize })
+
+	tests := []struct {
+		name      string
+		onFailure string
+		batchSize int
+		wantErr   string
+	}{
+		{"unknown failure policy", "contnue", 1, `invalid --on-failure "contnue"`},
+		{"zero batch size", onFailureStop, 0, "--batch-size must be positive"},
+		{"negative batch size", onFailureContinue, -1, "--batch-size must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execOnFailure, execBatchSize = tt.onFailure, tt.batchSize
+			err := runExec(nil, []string{"true"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runExec() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchTaskIDs(t *testing.T) {
+	running := []string{"0123abcd", "0123ffff", "4567abcd"}
+	tests := []struct {
+		ids     []string
+		want    []string
+		wantErr string
+	}{
+		{[]string{"4567abcd"}, []string{"4567abcd"}, ""},
+		{[]string{"4567", "0123a"}, []string{"4567abcd", "0123abcd"}, ""},
+		{[]string{"4567", "4567abcd", "4567"}, []string{"4567abcd"}, ""},
+		{[]string{"0123"}, nil, "ambiguous"},
+		{[]string{"89"}, nil, "no running task matches 89"},
+	}
+	for _, tt := range tests {
+		got, err := matchTaskIDs(running, tt.ids)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("matchTaskIDs(%v) error = %v, want %q", tt.ids, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("matchTaskIDs(%v) = %v, %v, want %v", tt.ids, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	portForwardingDocument       = "AWS-StartPortForwardingSession"
+	remotePortForwardingDocument = "AWS-StartPortForwardingSessionToRemoteHost"
+	startSessionAttempts         = 3
+	terminateSessionTimeout      = 10 * time.Second
+)
+
+// portForward is a single -L specification.
+type portForward struct {
+	bindAddress string
+	localPort   int
+	remoteHost  string // empty means the task itself
+	remotePort  int
+}
+
+func (f portForward) String() string {
+	remoteHost := f.remoteHost
+	if remoteHost == "" {
+		remoteHost = "task"
+	}
+	return fmt.Sprintf("%s -> %s", net.JoinHostPort(f.bindAddress, strconv.Itoa(f.localPort)), net.JoinHostPort(remoteHost, strconv.Itoa(f.remotePort)))
+}
+
+// parsePortForward parses [bind:]localPort:host:remotePort or
+// localPort:remotePort (a port on the task itself). IPv6 addresses are
+// written in brackets, e.g. [::1]:8080:db:5432.
+func parsePortForward(spec string) (portForward, error) {
+	parts, err := splitForwardSpec(spec)
+	if err != nil {
+		return portForward{}, err
+	}
+	forward := portForward{bindAddress: "127.0.0.1"}
+
+	var localPort, remotePort string
+	switch len(parts) {
+	case 2:
+		localPort, remotePort = parts[0], parts[1]
+	case 3:
+		localPort, forward.remoteHost, remotePort = parts[0], parts[1], parts[2]
+	case 4:
+		forward.bindAddress, localPort, forward.remoteHost, remotePort = parts[0], parts[1], parts[2], parts[3]
+	default:
+		return portForward{}, fmt.Errorf("invalid forward %q (want [bind:]localPort:host:remotePort)", spec)
+	}
+
+	if forward.localPort, err = parsePort(localPort); err != nil {
+		return portForward{}, fmt.Errorf("invalid local port in %q: %w", spec, err)
+	}
+	if forward.remotePort, err = parsePort(remotePort); err != nil {
+		return portForward{}, fmt.Errorf("invalid remote port in %q: %w", spec, err)
+	}
+	if len(parts) >= 3 && forward.remoteHost == "" {
+		return portForward{}, fmt.Errorf("missing remote host in %q", spec)
+	}
+
+	return forward, nil
+}
+
+// splitForwardSpec splits spec at colons outside of brackets and removes the
+// brackets around IPv6 addresses.
+func splitForwardSpec(spec string) ([]string, error) {
+	var parts []string
+	for {
+		if strings.HasPrefix(spec, "[") {
+			end := strings.Index(spec, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid forward %q: missing ]", spec)
+			}
+			rest := spec[end+1:]
+			if rest != "" && !strings.HasPrefix(rest, ":") {
+				return nil, fmt.Errorf("invalid forward %q: unexpected %q after ]", spec, rest)
+			}
+			parts = append(parts, spec[1:end])
+			if rest == "" {
+				return parts, nil
+			}
+			spec = rest[1:]
+			continue
+		}
+
+		i := strings.Index(spec, ":")
+		if i < 0 {
+			return append(parts, spec), nil
+		}
+		parts = append(parts, spec[:i])
+		spec = spec[i+1:]
+	}
+}
+
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port out of range: %d", port)
+	}
+	return port, nil
+}
+
+func runForward(cmd *cobra.Command, args []string) error {
+	if len(forwardSpecs) == 0 {
+		return fmt.Errorf("at least one -L forward is required")
+	}
+	var forwards []portForward
+	for _, spec := range forwardSpecs {
+		forward, err := parsePortForward(spec)
+		if err != nil {
+			return err
+		}
+		forwards = append(forwards, forward)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Select profile and cluster
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Select service
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	// Select task
+	selectedTask, err := selectTask(ctx, cfg, ecsClient, selectedCluster, selectedService)
+	if err != nil {
+		return fmt.Errorf("failed to select task: %w", err)
+	}
+
+	// Select container if not specified
+	selectedContainer := container
+	if selectedContainer == "" {
+		selectedContainer, err = selectContainer(ctx, ecsClient, selectedCluster, selectedTask)
+		if err != nil {
+			return fmt.Errorf("failed to select container: %w", err)
+		}
+	}
+
+	target, err := sessionTarget(ctx, ecsClient, selectedCluster, selectedTask, selectedContainer)
+	if err != nil {
+		return err
+	}
+
+	ssmClient := ssm.NewFromConfig(cfg)
+	var wg sync.WaitGroup
+	for _, forward := range forwards {
+		listener, err := net.Listen("tcp", net.JoinHostPort(forward.bindAddress, strconv.Itoa(forward.localPort)))
+		if err != nil {
+			return fmt.Errorf("failed to listen for %s: %w", forward, err)
+		}
+		fmt.Printf("Forwarding %s via task %s\n", forward, selectedTask)
+
+		wg.Add(1)
+		go func(forward portForward, listener net.Listener) {
+			defer wg.Done()
+			serveForward(ctx, ssmClient, target, forward, listener)
+		}(forward, listener)
+	}
+
+	fmt.Println("Press Ctrl+C to stop.")
+	wg.Wait()
+	return nil
+}
+
+// sessionTarget returns the Session Manager target of a container, in the
+// form ecs:<cluster>_<taskId>_<runtimeId>.
+func sessionTarget(ctx context.Context, client *ecs.Client, clusterName, taskID, containerName string) (string, error) {
+	describeOutput, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(clusterName),
+		Tasks:   []string{taskID},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(describeOutput.Tasks) == 0 {
+		return "", fmt.Errorf("task not found: %s", taskID)
+	}
+
+	for _, c := range describeOutput.Tasks[0].Containers {
+		if aws.ToString(c.Name) == containerName {
+			if c.RuntimeId == nil {
+				return "", fmt.Errorf("container %s has no runtime ID yet", containerName)
+			}
+			return fmt.Sprintf("ecs:%s_%s_%s", clusterName, taskIDFromArn(taskID), aws.ToString(c.RuntimeId)), nil
+		}
+	}
+	return "", fmt.Errorf("container %s not found in task %s", containerName, taskID)
+}
+
+// serveForward accepts local connections until ctx is cancelled. Every
+// connection gets its own port forwarding session, so a dropped session
+// only affects the connection that used it.
+func serveForward(ctx context.Context, client *ssm.Client, target string, forward portForward, listener net.Listener) {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Stopped forwarding %s: %v\n", forward, err)
+			}
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			if err := forwardConnection(ctx, client, target, forward, conn); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Connection from %s (%s): %v\n", conn.RemoteAddr(), forward, err)
+			}
+		}()
+	}
+}
+
+// forwardConnection relays one local connection through a new session.
+func forwardConnection(ctx context.Context, client *ssm.Client, target string, forward portForward, conn net.Conn) error {
+	input := &ssm.StartSessionInput{
+		Target:       aws.String(target),
+		DocumentName: aws.String(portForwardingDocument),
+		Parameters: map[string][]string{
+			"portNumber":      {strconv.Itoa(forward.remotePort)},
+			"localPortNumber": {strconv.Itoa(forward.localPort)},
+		},
+	}
+	if forward.remoteHost != "" {
+		input.DocumentName = aws.String(remotePortForwardingDocument)
+		input.Parameters["host"] = []string{forward.remoteHost}
+	}
+
+	// Retry transient failures such as throttling with a short backoff
+	var output *ssm.StartSessionOutput
+	var err error
+	for attempt := 1; attempt <= startSessionAttempts; attempt++ {
+		output, err = client.StartSession(ctx, input)
+		if err == nil || ctx.Err() != nil || attempt == startSessionAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to start session: %w", err)
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+
+	// ctx is usually cancelled by now, so terminate with a context of its own
+	defer func() {
+		terminateCtx, cancel := context.WithTimeout(context.Background(), terminateSessionTimeout)
+		defer cancel()
+		client.TerminateSession(terminateCtx, &ssm.TerminateSessionInput{
+			SessionId: output.SessionId,
+		})
+	}()
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dc, err := openDataChannel(sessionCtx, aws.ToString(output.StreamUrl), aws.ToString(output.TokenValue), conn, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer dc.Close()
+
+	select {
+	case <-dc.Handshake():
+	case <-dc.Done():
+		return dc.Wait()
+	}
+
+	// Close the local connection when the session ends, and end the
+	// session when the local connection is closed
+	go func() {
+		<-dc.Done()
+		conn.Close()
+	}()
+	forwardInput(dc, conn)
+
+	select {
+	case <-dc.Done():
+		return dc.Wait()
+	default:
+		dc.sendFlag(flagDisconnectToPort)
+		dc.sendFlag(flagTerminateSession)
+		return nil
+	}
+}
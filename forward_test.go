@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParsePortForward(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    portForward
+		wantErr bool
+	}{
+		{"8080:80", portForward{bindAddress: "127.0.0.1", localPort: 8080, remotePort: 80}, false},
+		{"5432:db.internal:5432", portForward{bindAddress: "127.0.0.1", localPort: 5432, remoteHost: "db.internal", remotePort: 5432}, false},
+		{"0.0.0.0:6379:cache:6379", portForward{bindAddress: "0.0.0.0", localPort: 6379, remoteHost: "cache", remotePort: 6379}, false},
+		{"[::1]:5432:db:5432", portForward{bindAddress: "::1", localPort: 5432, remoteHost: "db", remotePort: 5432}, false},
+		{"[::]:8080:[fd00::10]:80", portForward{bindAddress: "::", localPort: 8080, remoteHost: "fd00::10", remotePort: 80}, false},
+		{"5432:[fd00::10]:5432", portForward{bindAddress: "127.0.0.1", localPort: 5432, remoteHost: "fd00::10", remotePort: 5432}, false},
+		{"::1:5432:db:5432", portForward{}, true},
+		{"[::1:5432:db:5432", portForward{}, true},
+		{"[::1]x:5432:db:5432", portForward{}, true},
+		{"8080", portForward{}, true},
+		{"8080::80", portForward{}, true},
+		{"http:80", portForward{}, true},
+		{"8080:70000", portForward{}, true},
+		{"0:80", portForward{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parsePortForward(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePortForward(%q) error = %v, wantErr %t", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePortForward(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestPortForwardString(t *testing.T) {
+	tests := []struct {
+		forward portForward
+		want    string
+	}{
+		{portForward{bindAddress: "127.0.0.1", localPort: 8080, remotePort: 80}, "127.0.0.1:8080 -> task:80"},
+		{portForward{bindAddress: "::1", localPort: 5432, remoteHost: "fd00::10", remotePort: 5432}, "[::1]:5432 -> [fd00::10]:5432"},
+	}
+	for _, tt := range tests {
+		if got := tt.forward.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Reads from a terminal cannot be cancelled, so a session that ends leaves
+// its reader blocked on stdin, and that reader swallows the next keystroke
+// meant for whatever runs afterwards. Instead stdin is read by a single
+// goroutine, and each consumer takes the chunks it needs from it.
+var (
+	stdinOnce    sync.Once
+	stdinStarted atomic.Bool
+	stdinChunks  chan []byte
+)
+
+func sharedStdin() <-chan []byte {
+	stdinOnce.Do(func() {
+		stdinStarted.Store(true)
+		stdinChunks = make(chan []byte)
+		go func() {
+			defer close(stdinChunks)
+			buf := make([]byte, streamChunkSize)
+			for {
+				n, err := os.Stdin.Read(buf)
+				if n > 0 {
+					chunk := make([]byte, n)
+					copy(chunk, buf[:n])
+					stdinChunks <- chunk
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	})
+	return stdinChunks
+}
+
+// stdinReader reads the shared stdin until done is closed.
+type stdinReader struct {
+	done    <-chan struct{}
+	pending []byte
+}
+
+func newStdinReader(done <-chan struct{}) *stdinReader {
+	return &stdinReader{done: done}
+}
+
+func (r *stdinReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		select {
+		case chunk, ok := <-sharedStdin():
+			if !ok {
+				return 0, io.EOF
+			}
+			r.pending = chunk
+		case <-r.done:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Close does nothing; the shared reader keeps running.
+func (r *stdinReader) Close() error {
+	return nil
+}
+
+// promptStdin returns the stdin to give promptui once the shared reader is
+// running, or nil to let it read stdin itself.
+func promptStdin() io.ReadCloser {
+	if !stdinStarted.Load() {
+		return nil
+	}
+	return newStdinReader(nil)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of resources ecsy ls can list
+var listKinds = []string{"clusters", "services", "tasks", "containers"}
+
+func runList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Select profile and clusters; --cluster narrows them down
+	_, ecsClient, clusterNames, err := listClustersWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	var l listing
+	switch args[0] {
+	case "clusters":
+		l, err = listClusters(ctx, ecsClient, clusterNames)
+	case "services":
+		l, err = listServices(ctx, ecsClient, clusterNames)
+	case "tasks":
+		l, err = listTasks(ctx, ecsClient, clusterNames)
+	case "containers":
+		l, err = listContainers(ctx, ecsClient, clusterNames)
+	default:
+		return fmt.Errorf("unknown resource %q (want %s)", args[0], strings.Join(listKinds, ", "))
+	}
+	if err != nil {
+		return err
+	}
+
+	return printListing(os.Stdout, l, outputFormat, listColumns, listSort)
+}
+
+func listClusters(ctx context.Context, client *ecs.Client, clusterNames []string) (listing, error) {
+	l := listing{
+		columns:        []string{"name", "status", "services", "running", "pending", "arn"},
+		defaultColumns: []string{"name", "status", "services", "running", "pending"},
+	}
+
+	for start := 0; start < len(clusterNames); start += 100 {
+		end := start + 100
+		if end > len(clusterNames) {
+			end = len(clusterNames)
+		}
+
+		describeOutput, err := client.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+			Clusters: clusterNames[start:end],
+		})
+		if err != nil {
+			return listing{}, fmt.Errorf("failed to describe clusters: %w", err)
+		}
+		for _, c := range describeOutput.Clusters {
+			l.rows = append(l.rows, map[string]interface{}{
+				"name":     aws.ToString(c.ClusterName),
+				"status":   aws.ToString(c.Status),
+				"services": c.ActiveServicesCount,
+				"running":  c.RunningTasksCount,
+				"pending":  c.PendingTasksCount,
+				"arn":      aws.ToString(c.ClusterArn),
+			})
+		}
+	}
+
+	return l, nil
+}
+
+func listServices(ctx context.Context, client *ecs.Client, clusterNames []string) (listing, error) {
+	l := listing{
+		columns:        []string{"cluster", "name", "status", "desired", "running", "pending", "launch", "taskdef", "exec", "arn"},
+		defaultColumns: []string{"cluster", "name", "status", "desired", "running", "pending", "taskdef"},
+	}
+
+	for _, clusterName := range clusterNames {
+		services, err := describeClusterServices(ctx, client, clusterName)
+		if err != nil {
+			return listing{}, err
+		}
+		for _, s := range services {
+			l.rows = append(l.rows, map[string]interface{}{
+				"cluster": clusterName,
+				"name":    aws.ToString(s.ServiceName),
+				"status":  aws.ToString(s.Status),
+				"desired": s.DesiredCount,
+				"running": s.RunningCount,
+				"pending": s.PendingCount,
+				"launch":  serviceLaunchLabel(s),
+				"taskdef": taskDefinitionName(aws.ToString(s.TaskDefinition)),
+				"exec":    s.EnableExecuteCommand,
+				"arn":     aws.ToString(s.ServiceArn),
+			})
+		}
+	}
+
+	return l, nil
+}
+
+// describeClusterServices returns the services of a cluster matching
+// --service.
+func describeClusterServices(ctx context.Context, client *ecs.Client, clusterName string) ([]types.Service, error) {
+	serviceNames := []string{service}
+	if service == "" || isNamePattern(service) {
+		names, err := listServiceNames(ctx, client, clusterName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list services in %s: %w", clusterName, err)
+		}
+		serviceNames, err = filterNames(service, names)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var services []types.Service
+	for start := 0; start < len(serviceNames); start += 10 {
+		end := start + 10
+		if end > len(serviceNames) {
+			end = len(serviceNames)
+		}
+
+		describeOutput, err := client.DescribeServices(ctx, &ecs.DescribeServicesInput{
+			Cluster:  aws.String(clusterName),
+			Services: serviceNames[start:end],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe services in %s: %w", clusterName, err)
+		}
+		services = append(services, describeOutput.Services...)
+	}
+
+	return services, nil
+}
+
+func listTasks(ctx context.Context, client *ecs.Client, clusterNames []string) (listing, error) {
+	l := listing{
+		columns:        []string{"cluster", "service", "id", "status", "health", "taskdef", "launch", "az", "ip", "started", "startedby", "arn"},
+		defaultColumns: []string{"cluster", "service", "id", "status", "health", "taskdef", "ip", "started"},
+	}
+
+	for _, clusterName := range clusterNames {
+		tasks, err := listMatchingTasks(ctx, client, clusterName)
+		if err != nil {
+			return listing{}, err
+		}
+		for _, t := range tasks {
+			l.rows = append(l.rows, map[string]interface{}{
+				"cluster":   clusterName,
+				"service":   serviceNameFromGroup(aws.ToString(t.Group)),
+				"id":        taskIDFromArn(aws.ToString(t.TaskArn)),
+				"status":    aws.ToString(t.LastStatus),
+				"health":    string(t.HealthStatus),
+				"taskdef":   taskDefinitionName(aws.ToString(t.TaskDefinitionArn)),
+				"launch":    taskLaunchLabel(t),
+				"az":        aws.ToString(t.AvailabilityZone),
+				"ip":        taskPrivateIP(t),
+				"started":   formatTimestamp(t.StartedAt),
+				"startedby": aws.ToString(t.StartedBy),
+				"arn":       aws.ToString(t.TaskArn),
+			})
+		}
+	}
+
+	return l, nil
+}
+
+func listContainers(ctx context.Context, client *ecs.Client, clusterNames []string) (listing, error) {
+	l := listing{
+		columns:        []string{"cluster", "service", "task", "name", "status", "health", "image", "digest", "exitcode", "runtimeid"},
+		defaultColumns: []string{"cluster", "service", "task", "name", "status", "health", "image"},
+	}
+
+	for _, clusterName := range clusterNames {
+		tasks, err := listMatchingTasks(ctx, client, clusterName)
+		if err != nil {
+			return listing{}, err
+		}
+		for _, t := range tasks {
+			for _, c := range t.Containers {
+				if container != "" && aws.ToString(c.Name) != container {
+					continue
+				}
+				var exitCode interface{}
+				if c.ExitCode != nil {
+					exitCode = *c.ExitCode
+				}
+				l.rows = append(l.rows, map[string]interface{}{
+					"cluster":   clusterName,
+					"service":   serviceNameFromGroup(aws.ToString(t.Group)),
+					"task":      taskIDFromArn(aws.ToString(t.TaskArn)),
+					"name":      aws.ToString(c.Name),
+					"status":    aws.ToString(c.LastStatus),
+					"health":    string(c.HealthStatus),
+					"image":     aws.ToString(c.Image),
+					"digest":    aws.ToString(c.ImageDigest),
+					"exitcode":  exitCode,
+					"runtimeid": aws.ToString(c.RuntimeId),
+				})
+			}
+		}
+	}
+
+	return l, nil
+}
+
+// listMatchingTasks returns the running tasks of a cluster matching
+// --service and --task (an ID prefix).
+func listMatchingTasks(ctx context.Context, client *ecs.Client, clusterName string) ([]types.Task, error) {
+	tasks, err := listRunningTasks(ctx, client, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks in %s: %w", clusterName, err)
+	}
+
+	var matched []types.Task
+	for _, t := range tasks {
+		ok, err := matchName(service, serviceNameFromGroup(aws.ToString(t.Group)))
+		if err != nil {
+			return nil, err
+		}
+		if ok && strings.HasPrefix(taskIDFromArn(aws.ToString(t.TaskArn)), task) {
+			matched = append(matched, t)
+		}
+	}
+	return matched, nil
+}
+
+// serviceNameFromGroup returns the service of a task group "service:NAME",
+// or "" for tasks not started by a service.
+func serviceNameFromGroup(group string) string {
+	if name, ok := strings.CutPrefix(group, "service:"); ok {
+		return name
+	}
+	return ""
+}
+
+// taskDefinitionName returns family:revision of a task definition ARN.
+func taskDefinitionName(arn string) string {
+	parts := strings.Split(arn, "/")
+	return parts[len(parts)-1]
+}
+
+func serviceLaunchLabel(s types.Service) string {
+	if len(s.CapacityProviderStrategy) > 0 {
+		var providers []string
+		for _, item := range s.CapacityProviderStrategy {
+			providers = append(providers, aws.ToString(item.CapacityProvider))
+		}
+		return strings.Join(providers, ",")
+	}
+	return string(s.LaunchType)
+}
+
+func taskLaunchLabel(t types.Task) string {
+	if t.CapacityProviderName != nil {
+		return aws.ToString(t.CapacityProviderName)
+	}
+	return string(t.LaunchType)
+}
+
+// taskPrivateIP returns the private IPv4 address of a task's network
+// interface, if any.
+func taskPrivateIP(t types.Task) string {
+	for _, attachment := range t.Attachments {
+		for _, detail := range attachment.Details {
+			if aws.ToString(detail.Name) == "privateIPv4Address" {
+				return aws.ToString(detail.Value)
+			}
+		}
+	}
+	for _, c := range t.Containers {
+		for _, ni := range c.NetworkInterfaces {
+			if ni.PrivateIpv4Address != nil {
+				return aws.ToString(ni.PrivateIpv4Address)
+			}
+		}
+	}
+	return ""
+}
+
+func formatTimestamp(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
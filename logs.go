@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+const logsPollInterval = 2 * time.Second
+
+// logSource is where the awslogs driver writes a container's output.
+type logSource struct {
+	group   string
+	region  string
+	streams map[string]string // stream name -> task ID
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	var pattern *regexp.Regexp
+	if logsGrep != "" {
+		var err error
+		pattern, err = regexp.Compile(logsGrep)
+		if err != nil {
+			return fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Select profile and cluster
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Select service
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	// Select one task, or all running tasks of the service
+	var taskIDs []string
+	if logsAll {
+		taskIDs, err = selectFanoutTasks(ctx, ecsClient, selectedCluster, selectedService, nil)
+		if err != nil {
+			return fmt.Errorf("failed to select tasks: %w", err)
+		}
+	} else {
+		selectedTask, err := selectTask(ctx, cfg, ecsClient, selectedCluster, selectedService)
+		if err != nil {
+			return fmt.Errorf("failed to select task: %w", err)
+		}
+		taskIDs = []string{selectedTask}
+	}
+
+	// Select container if not specified
+	selectedContainer := container
+	if selectedContainer == "" {
+		selectedContainer, err = selectContainer(ctx, ecsClient, selectedCluster, taskIDs[0])
+		if err != nil {
+			return fmt.Errorf("failed to select container: %w", err)
+		}
+	}
+
+	source, err := findLogSource(ctx, ecsClient, selectedCluster, taskIDs, selectedContainer)
+	if err != nil {
+		return err
+	}
+	if source.region != "" {
+		cfg = cfg.Copy()
+		cfg.Region = source.region
+	}
+
+	return tailLogs(ctx, cloudwatchlogs.NewFromConfig(cfg), source, pattern, len(taskIDs) > 1)
+}
+
+// findLogSource reads the awslogs configuration of a container from the
+// task definition and derives the log stream of each task,
+// prefix/container/taskId.
+func findLogSource(ctx context.Context, client *ecs.Client, clusterName string, taskIDs []string, containerName string) (logSource, error) {
+	tasks, err := describeTasks(ctx, client, clusterName, taskIDs)
+	if err != nil {
+		return logSource{}, fmt.Errorf("failed to describe tasks: %w", err)
+	}
+	if len(tasks) == 0 {
+		return logSource{}, fmt.Errorf("no tasks found")
+	}
+
+	source := logSource{streams: make(map[string]string)}
+	configs := make(map[string]awslogsConfig)
+	for _, t := range tasks {
+		taskDefinition := aws.ToString(t.TaskDefinitionArn)
+		logConfig, ok := configs[taskDefinition]
+		if !ok {
+			logConfig, err = readAwslogsConfig(ctx, client, taskDefinition, containerName)
+			if err != nil {
+				return logSource{}, err
+			}
+			configs[taskDefinition] = logConfig
+		}
+
+		if source.group != "" && (source.group != logConfig.group || source.region != logConfig.region) {
+			return logSource{}, fmt.Errorf("tasks log to different log groups (%s and %s)", source.group, logConfig.group)
+		}
+		source.group, source.region = logConfig.group, logConfig.region
+
+		taskID := taskIDFromArn(aws.ToString(t.TaskArn))
+		source.streams[fmt.Sprintf("%s/%s/%s", logConfig.prefix, containerName, taskID)] = taskID
+	}
+
+	return source, nil
+}
+
+// awslogsConfig is the awslogs log driver configuration of a container.
+type awslogsConfig struct {
+	group  string
+	region string
+	prefix string
+}
+
+func readAwslogsConfig(ctx context.Context, client *ecs.Client, taskDefinition, containerName string) (awslogsConfig, error) {
+	taskDef, _, err := describeTaskDefinition(ctx, client, taskDefinition)
+	if err != nil {
+		return awslogsConfig{}, err
+	}
+
+	for _, c := range taskDef.ContainerDefinitions {
+		if aws.ToString(c.Name) != containerName {
+			continue
+		}
+		if c.LogConfiguration == nil || c.LogConfiguration.LogDriver != types.LogDriverAwslogs {
+			return awslogsConfig{}, fmt.Errorf("container %s does not use the awslogs log driver", containerName)
+		}
+
+		options := c.LogConfiguration.Options
+		logConfig := awslogsConfig{
+			group:  options["awslogs-group"],
+			region: options["awslogs-region"],
+			prefix: options["awslogs-stream-prefix"],
+		}
+		if logConfig.group == "" {
+			return awslogsConfig{}, fmt.Errorf("container %s has no awslogs-group", containerName)
+		}
+		if logConfig.prefix == "" {
+			// Without a prefix the stream is named after the container ID
+			return awslogsConfig{}, fmt.Errorf("container %s has no awslogs-stream-prefix, so its log stream cannot be derived", containerName)
+		}
+		return logConfig, nil
+	}
+
+	return awslogsConfig{}, fmt.Errorf("container %s not found in task definition %s", containerName, taskDefinition)
+}
+
+// tailLogs prints the events of the source's streams since --since, and
+// keeps polling for new events with --follow.
+func tailLogs(ctx context.Context, client *cloudwatchlogs.Client, source logSource, pattern *regexp.Regexp, prefixTasks bool) error {
+	var streamNames []string
+	for stream := range source.streams {
+		streamNames = append(streamNames, stream)
+	}
+	sort.Strings(streamNames)
+
+	startTime := time.Now().Add(-logsSince).UnixMilli()
+	seen := make(map[string]bool)
+
+	for {
+		events, err := filterLogEvents(ctx, client, source.group, streamNames, startTime)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read logs from %s: %w", source.group, err)
+		}
+
+		// Events of several streams arrive grouped by stream
+		sort.SliceStable(events, func(i, j int) bool {
+			return aws.ToInt64(events[i].Timestamp) < aws.ToInt64(events[j].Timestamp)
+		})
+
+		// Poll again from the newest timestamp, skipping events already shown
+		newSeen := make(map[string]bool)
+		for _, event := range events {
+			timestamp := aws.ToInt64(event.Timestamp)
+			if timestamp > startTime {
+				startTime = timestamp
+				newSeen = make(map[string]bool)
+			}
+			if timestamp == startTime {
+				newSeen[aws.ToString(event.EventId)] = true
+			}
+			if seen[aws.ToString(event.EventId)] {
+				continue
+			}
+
+			message := strings.TrimRight(aws.ToString(event.Message), "\n")
+			if pattern != nil && !pattern.MatchString(message) {
+				continue
+			}
+			line := fmt.Sprintf("%s %s", time.UnixMilli(timestamp).Format("2006-01-02T15:04:05.000"), message)
+			if prefixTasks {
+				line = fmt.Sprintf("[%s] %s", shortTaskID(source.streams[aws.ToString(event.LogStreamName)]), line)
+			}
+			fmt.Println(line)
+		}
+		if len(events) > 0 {
+			seen = newSeen
+		}
+
+		if !logsFollow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logsPollInterval):
+		}
+	}
+}
+
+// filterLogEvents returns all events of the streams from startTime on.
+func filterLogEvents(ctx context.Context, client *cloudwatchlogs.Client, group string, streams []string, startTime int64) ([]logtypes.FilteredLogEvent, error) {
+	var events []logtypes.FilteredLogEvent
+
+	// A request accepts at most 100 streams
+	for start := 0; start < len(streams); start += 100 {
+		end := start + 100
+		if end > len(streams) {
+			end = len(streams)
+		}
+
+		var nextToken *string
+		for {
+			output, err := client.FilterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+				LogGroupName:   aws.String(group),
+				LogStreamNames: streams[start:end],
+				StartTime:      aws.Int64(startTime),
+				NextToken:      nextToken,
+			})
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, output.Events...)
+
+			if output.NextToken == nil {
+				break
+			}
+			nextToken = output.NextToken
+		}
+	}
+
+	return events, nil
+}
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -18,6 +19,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/manifoldco/promptui"
@@ -35,6 +37,80 @@ var (
 	task      string
 	command   string
 	container string
+
+	// exec flags
+	execAll         bool
+	execTasks       []string
+	execMaxParallel int
+	execStaged      bool
+	execBatchSize   int
+	execOnFailure   string
+
+	// forward flags
+	forwardSpecs []string
+
+	// sync flags
+	syncWatch       bool
+	syncIncludes    []string
+	syncExcludes    []string
+	syncPostCommand string
+	syncInterval    time.Duration
+
+	// debug flags
+	debugImage   string
+	debugReplace string
+
+	// cleanup flags
+	ephemeral   bool
+	gcOlderThan time.Duration
+	gcAllUsers  bool
+
+	// logs flags
+	logsAll    bool
+	logsFollow bool
+	logsGrep   string
+	logsSince  time.Duration
+
+	// events flags
+	eventsLimit   int
+	eventsStopped int
+
+	// deployment flags
+	deployTimeout time.Duration
+
+	// scale flags
+	scaleAdjust bool
+
+	// stop flags
+	stopReason string
+	stopWait   bool
+	stopForce  bool
+
+	// deploy flags
+	deployImages []string
+
+	// rollback flags
+	rollbackLimit int
+
+	// metrics flags
+	pickerMetrics bool
+
+	// top flags
+	topInterval time.Duration
+
+	// output flags
+	outputFormat string
+	listColumns  string
+	listSort     string
+
+	// describe flags
+	describeFormat string
+
+	// recording flags
+	recordFile      string
+	recordInputFlag bool
+	replaySpeed     float64
+	replayMaxIdle   time.Duration
 )
 
 func main() {
@@ -44,12 +120,16 @@ func main() {
 		RunE:  run,
 	}
 
-	rootCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile name")
-	rootCmd.Flags().StringVarP(&cluster, "cluster", "c", "", "ECS cluster name")
-	rootCmd.Flags().StringVarP(&service, "service", "s", "", "ECS service name")
-	rootCmd.Flags().StringVarP(&task, "task", "t", "", "ECS task ID")
-	rootCmd.Flags().StringVar(&command, "command", "/bin/sh", "Command to execute")
-	rootCmd.Flags().StringVar(&container, "container", "", "Container name to execute command in")
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", "AWS profile name")
+	rootCmd.PersistentFlags().StringVarP(&cluster, "cluster", "c", "", "ECS cluster name (glob or /regex/ allowed)")
+	rootCmd.PersistentFlags().StringVarP(&service, "service", "s", "", "ECS service name (glob or /regex/ allowed)")
+	rootCmd.PersistentFlags().StringVarP(&task, "task", "t", "", "ECS task ID")
+	rootCmd.Flags().StringVar(&command, "command", autoShell, "Command to execute (auto: the best shell available in the container)")
+	rootCmd.PersistentFlags().StringVar(&container, "container", "", "Container name to execute command in")
+	rootCmd.Flags().BoolVar(&pickerMetrics, "metrics", false, "Show recent CPU and memory usage in the task picker")
+	rootCmd.PersistentFlags().BoolVar(&ephemeral, "ephemeral", false, "Stop tasks started by ecsy without asking when the command ends")
+	rootCmd.Flags().StringVar(&recordFile, "record", "", "Record the session to an asciicast v2 file")
+	rootCmd.Flags().BoolVar(&recordInputFlag, "record-input", false, "Also record keyboard input")
 
 	// Add version command
 	versionCmd := &cobra.Command{
@@ -69,7 +149,255 @@ func main() {
 	}
 	rootCmd.AddCommand(updateCmd)
 
-	if err := rootCmd.Execute(); err != nil {
+	// Add exec command
+	execCmd := &cobra.Command{
+		Use:           "exec -- <command> [args...]",
+		Short:         "Run a command non-interactively and return its exit code",
+		Args:          cobra.MinimumNArgs(1),
+		RunE:          runExec,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	execCmd.Flags().BoolVar(&execAll, "all", false, "Run on every running task of the service")
+	execCmd.Flags().StringSliceVar(&execTasks, "tasks", nil, "Run on these task IDs (or ID prefixes), comma separated")
+	execCmd.Flags().IntVar(&execMaxParallel, "max-parallel", 10, "Maximum number of tasks to run on at once with --all/--tasks")
+	execCmd.Flags().BoolVar(&execStaged, "staged", false, "Run on one canary task first, confirm, then continue in batches")
+	execCmd.Flags().IntVar(&execBatchSize, "batch-size", 1, "Number of tasks per batch after the canary with --staged")
+	execCmd.Flags().StringVar(&execOnFailure, "on-failure", "stop", "What to do when a task fails with --staged: stop or continue")
+	execCmd.Flags().BoolVar(&pickerMetrics, "metrics", false, "Show recent CPU and memory usage in the task picker")
+	rootCmd.AddCommand(execCmd)
+
+	// Add forward command
+	forwardCmd := &cobra.Command{
+		Use:   "forward -L [bind:]localPort:host:remotePort",
+		Short: "Forward local ports through a task using Session Manager",
+		Args:  cobra.NoArgs,
+		RunE:  runForward,
+	}
+	forwardCmd.Flags().StringArrayVarP(&forwardSpecs, "local", "L", nil, "Forward [bind:]localPort:host:remotePort, or localPort:remotePort on the task itself; IPv6 addresses go in brackets (repeatable)")
+	forwardCmd.Flags().BoolVar(&pickerMetrics, "metrics", false, "Show recent CPU and memory usage in the task picker")
+	rootCmd.AddCommand(forwardCmd)
+
+	// Add cp command
+	copyCmd := &cobra.Command{
+		Use:   "cp <src> <dst>",
+		Short: "Copy files and directories to or from a container (container:path)",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runCopy,
+	}
+	copyCmd.Flags().BoolVar(&pickerMetrics, "metrics", false, "Show recent CPU and memory usage in the task picker")
+	rootCmd.AddCommand(copyCmd)
+
+	// Add sync command
+	syncCmd := &cobra.Command{
+		Use:   "sync <local-dir> <container:path>",
+		Short: "Push changed files from a local directory into a container",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runSync,
+	}
+	syncCmd.Flags().BoolVarP(&syncWatch, "watch", "w", false, "Keep watching for changes and push them")
+	syncCmd.Flags().StringArrayVar(&syncIncludes, "include", nil, "Only sync files matching this glob (repeatable)")
+	syncCmd.Flags().StringArrayVar(&syncExcludes, "exclude", nil, "Skip files and directories matching this glob (repeatable)")
+	syncCmd.Flags().StringVar(&syncPostCommand, "post-sync", "", "Command to run in the remote directory after each sync")
+	syncCmd.Flags().DurationVar(&syncInterval, "interval", time.Second, "How often to check for changes with --watch")
+	syncCmd.Flags().BoolVar(&pickerMetrics, "metrics", false, "Show recent CPU and memory usage in the task picker")
+	rootCmd.AddCommand(syncCmd)
+
+	// Add debug command
+	debugCmd := &cobra.Command{
+		Use:          "debug",
+		Short:        "Start a copy of a service's task with a toolbox container and connect to it",
+		SilenceUsage: true,
+		RunE:         runDebug,
+	}
+	debugCmd.Flags().StringVar(&debugImage, "image", "", "Toolbox image (default "+defaultDebugImage+", or debug_image in ~/.ecsy/config)")
+	debugCmd.Flags().StringVar(&debugReplace, "replace", "", "Replace this container with the toolbox instead of adding it")
+	debugCmd.Flags().StringVar(&command, "command", autoShell, "Command to execute in the toolbox")
+	rootCmd.AddCommand(debugCmd)
+
+	// Add describe command
+	describeCmd := &cobra.Command{
+		Use:          "describe task|service [id]",
+		Short:        "Show a summary of a task or service",
+		Args:         cobra.RangeArgs(1, 2),
+		ValidArgs:    []string{"task", "service"},
+		SilenceUsage: true,
+		RunE:         runDescribe,
+	}
+	describeCmd.Flags().StringVarP(&describeFormat, "output", "o", "", "Print the raw API structure as json or yaml")
+	describeCmd.Flags().BoolVar(&pickerMetrics, "metrics", false, "Show recent CPU and memory usage in the task picker")
+	rootCmd.AddCommand(describeCmd)
+
+	// Add logs command
+	logsCmd := &cobra.Command{
+		Use:          "logs",
+		Short:        "Show container logs from CloudWatch Logs",
+		SilenceUsage: true,
+		RunE:         runLogs,
+	}
+	logsCmd.Flags().BoolVar(&logsAll, "all", false, "Show logs of all running tasks of the service")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep showing new log events")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Only show lines matching this regular expression")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 10*time.Minute, "Show log events newer than this duration")
+	logsCmd.Flags().BoolVar(&pickerMetrics, "metrics", false, "Show recent CPU and memory usage in the task picker")
+	rootCmd.AddCommand(logsCmd)
+
+	// Add events command
+	eventsCmd := &cobra.Command{
+		Use:          "events",
+		Short:        "Show service events and why recent tasks stopped",
+		SilenceUsage: true,
+		RunE:         runEvents,
+	}
+	eventsCmd.Flags().IntVarP(&eventsLimit, "limit", "n", 10, "Number of service events to show")
+	eventsCmd.Flags().IntVar(&eventsStopped, "stopped", 5, "Number of stopped tasks to show")
+	rootCmd.AddCommand(eventsCmd)
+
+	// Add restart command
+	restartCmd := &cobra.Command{
+		Use:          "restart",
+		Short:        "Restart a service with a new deployment and wait until it is stable",
+		SilenceUsage: true,
+		RunE:         runRestart,
+	}
+	restartCmd.Flags().DurationVar(&deployTimeout, "timeout", 10*time.Minute, "How long to wait for the service to become stable")
+	rootCmd.AddCommand(restartCmd)
+
+	// Add scale command
+	scaleCmd := &cobra.Command{
+		Use:          "scale <count>",
+		Short:        "Change the desired task count of a service and wait for it",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         runScale,
+	}
+	scaleCmd.Flags().BoolVar(&scaleAdjust, "adjust-limits", false, "Widen the auto scaling min/max to include the count")
+	scaleCmd.Flags().DurationVar(&deployTimeout, "timeout", 10*time.Minute, "How long to wait for the running count to converge")
+	rootCmd.AddCommand(scaleCmd)
+
+	// Add stop command
+	stopCmd := &cobra.Command{
+		Use:          "stop [task-id...]",
+		Short:        "Stop tasks of a service with a reason",
+		Long:         "Stop one or more tasks of a service. Without task IDs the tasks are picked from a list.\nStopping all tasks of a service at once is refused unless --force is given.",
+		SilenceUsage: true,
+		RunE:         runStop,
+	}
+	stopCmd.Flags().StringVar(&stopReason, "reason", "", "Reason for stopping the tasks, shown in the task's stopped reason (required)")
+	stopCmd.Flags().BoolVar(&stopWait, "wait", false, "Wait until the service has replaced the tasks")
+	stopCmd.Flags().BoolVar(&stopForce, "force", false, "Allow stopping all tasks of the service")
+	stopCmd.Flags().DurationVar(&deployTimeout, "timeout", 10*time.Minute, "How long to wait with --wait")
+	stopCmd.MarkFlagRequired("reason")
+	stopCmd.Flags().BoolVar(&pickerMetrics, "metrics", false, "Show recent CPU and memory usage in the task picker")
+	rootCmd.AddCommand(stopCmd)
+
+	// Add deploy command
+	deployCmd := &cobra.Command{
+		Use:          "deploy --image container=image",
+		Short:        "Deploy new container images to a service",
+		Long:         "Register a new revision of the service's task definition with the given images, update the service and watch the rollout.\nThe container name can be left out when the task definition has a single container.",
+		SilenceUsage: true,
+		RunE:         runDeploy,
+	}
+	deployCmd.Flags().StringArrayVar(&deployImages, "image", nil, "Image for a container as container=image (repeatable)")
+	deployCmd.Flags().DurationVar(&deployTimeout, "timeout", 10*time.Minute, "How long to wait for the service to become stable")
+	deployCmd.MarkFlagRequired("image")
+	rootCmd.AddCommand(deployCmd)
+
+	// Add rollback command
+	rollbackCmd := &cobra.Command{
+		Use:          "rollback [revision]",
+		Short:        "Roll a service back to a previous task definition revision",
+		Long:         "Roll a service back to another revision of its task definition family and watch the rollout.\nWithout a revision the recent revisions are listed, starting at the one before the current revision.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE:         runRollback,
+	}
+	rollbackCmd.Flags().IntVar(&rollbackLimit, "limit", 10, "Number of revisions to list")
+	rollbackCmd.Flags().DurationVar(&deployTimeout, "timeout", 10*time.Minute, "How long to wait for the service to become stable")
+	rootCmd.AddCommand(rollbackCmd)
+
+	// Add metrics command
+	metricsCmd := &cobra.Command{
+		Use:          "metrics",
+		Short:        "Show CPU and memory usage of a service's tasks",
+		Long:         "Show CPU and memory usage of a service's tasks and containers from Container Insights.\nCPU and MEMORY are percentages of the task or container limit, and the trends cover the last hour.\nTask and container metrics need Container Insights with enhanced observability.",
+		SilenceUsage: true,
+		RunE:         runMetrics,
+	}
+	metricsCmd.Flags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format: table, tsv, json or yaml")
+	metricsCmd.Flags().StringVar(&listColumns, "columns", "", "Comma separated columns to show, or all")
+	metricsCmd.Flags().StringVar(&listSort, "sort", "", "Column to sort by (prefix with - for descending order)")
+	rootCmd.AddCommand(metricsCmd)
+
+	// Add top command
+	topCmd := &cobra.Command{
+		Use:          "top",
+		Short:        "Show a live dashboard of the services and tasks in a cluster",
+		Long:         "Show a live dashboard of the services and tasks in a cluster.\nMove with the arrow keys or j/k, press Enter on a task to exec into it, r to refresh and q to quit.",
+		SilenceUsage: true,
+		RunE:         runTop,
+	}
+	topCmd.Flags().DurationVar(&topInterval, "interval", 5*time.Second, "Refresh interval")
+	rootCmd.AddCommand(topCmd)
+
+	// Add ls command
+	lsCmd := &cobra.Command{
+		Use:          "ls clusters|services|tasks|containers",
+		Short:        "List clusters, services, running tasks or containers",
+		Long:         "List clusters, services, running tasks or containers without prompting.\n--cluster and --service accept names or patterns and narrow down the results; --task matches task ID prefixes.",
+		Args:         cobra.ExactArgs(1),
+		ValidArgs:    listKinds,
+		SilenceUsage: true,
+		RunE:         runList,
+	}
+	lsCmd.Flags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format: table, tsv, json or yaml")
+	lsCmd.Flags().StringVar(&listColumns, "columns", "", "Comma separated columns to show, or all")
+	lsCmd.Flags().StringVar(&listSort, "sort", "", "Column to sort by (prefix with - for descending order)")
+	rootCmd.AddCommand(lsCmd)
+
+	// Add gc command
+	gcCmd := &cobra.Command{
+		Use:          "gc",
+		Short:        "Stop old tasks started by ecsy",
+		SilenceUsage: true,
+		RunE:         runGC,
+	}
+	gcCmd.Flags().DurationVar(&gcOlderThan, "older-than", 12*time.Hour, "Only stop tasks that have been running for longer than this")
+	gcCmd.Flags().BoolVar(&gcAllUsers, "all-users", false, "Include tasks started by other users")
+	rootCmd.AddCommand(gcCmd)
+
+	// Add doctor command
+	doctorCmd := &cobra.Command{
+		Use:          "doctor",
+		Short:        "Check why exec might not work for a service",
+		SilenceUsage: true,
+		RunE:         runDoctor,
+	}
+	rootCmd.AddCommand(doctorCmd)
+
+	// Add replay command
+	replayCmd := &cobra.Command{
+		Use:   "replay <file>",
+		Short: "Play back a session recorded with --record",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runReplay,
+	}
+	replayCmd.Flags().Float64Var(&replaySpeed, "speed", 1, "Playback speed multiplier")
+	replayCmd.Flags().DurationVar(&replayMaxIdle, "max-idle", 2*time.Second, "Cap pauses between output to this duration (0 for no cap)")
+	rootCmd.AddCommand(replayCmd)
+
+	err := rootCmd.Execute()
+
+	// Offer to stop tasks started for this session
+	cleanupStartedTasks()
+
+	if err != nil {
+		// Propagate the exit code of a remote command
+		var exitErr *remoteExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -78,60 +406,120 @@ func main() {
 func run(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	// Select profile and cluster
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Select service
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	// Select task
+	selectedTask, err := selectTask(ctx, cfg, ecsClient, selectedCluster, selectedService)
+	if err != nil {
+		return fmt.Errorf("failed to select task: %w", err)
+	}
+
+	// Execute command
+	return executeCommand(ctx, cfg, selectedCluster, selectedTask, selectedService)
+}
+
+// selectClusterWithAuth selects the AWS profile and ECS cluster, retrying
+// with MFA authentication when access is denied.
+func selectClusterWithAuth(ctx context.Context) (aws.Config, *ecs.Client, string, error) {
+	var selectedCluster string
+	cfg, ecsClient, err := loadConfigWithAuth(ctx, func(client *ecs.Client) error {
+		var err error
+		selectedCluster, err = selectCluster(ctx, client)
+		if err != nil {
+			return fmt.Errorf("failed to select cluster: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return aws.Config{}, nil, "", err
+	}
+
+	return cfg, ecsClient, selectedCluster, nil
+}
+
+// loadConfigWithAuth selects the AWS profile, loads its config and calls fn
+// with an ECS client. When fn fails because access is denied, the config is
+// reloaded with MFA authentication and fn is called again.
+func loadConfigWithAuth(ctx context.Context, fn func(client *ecs.Client) error) (aws.Config, *ecs.Client, error) {
 	// Select AWS profile
 	selectedProfile, err := selectProfile()
 	if err != nil {
-		return fmt.Errorf("failed to select profile: %w", err)
+		return aws.Config{}, nil, fmt.Errorf("failed to select profile: %w", err)
 	}
 
+	// Remember the selection for later steps such as session recording
+	profile = selectedProfile
+
 	// Load AWS config
 	cfg, err := loadAWSConfig(ctx, selectedProfile)
 	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
+		return aws.Config{}, nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
-
 	ecsClient := ecs.NewFromConfig(cfg)
 
-	// Select cluster
-	selectedCluster, err := selectCluster(ctx, ecsClient)
-	if err != nil {
-		// Check if error is due to MFA requirement
-		if strings.Contains(err.Error(), "explicit deny") || strings.Contains(err.Error(), "AccessDenied") {
-			fmt.Println("Access denied. Attempting MFA authentication...")
-			
-			// Reload config with MFA
-			cfg, err = loadAWSConfigWithMFA(ctx, selectedProfile)
-			if err != nil {
-				return fmt.Errorf("failed to load AWS config with MFA: %w", err)
-			}
-			
-			// Recreate ECS client with new config
-			ecsClient = ecs.NewFromConfig(cfg)
-			
-			// Retry cluster selection
-			selectedCluster, err = selectCluster(ctx, ecsClient)
-			if err != nil {
-				return fmt.Errorf("failed to select cluster after MFA: %w", err)
-			}
-		} else {
-			return fmt.Errorf("failed to select cluster: %w", err)
+	err = fn(ecsClient)
+	if err != nil && isAccessDenied(err) {
+		fmt.Fprintln(os.Stderr, "Access denied. Attempting MFA authentication...")
+
+		// Reload config with MFA
+		cfg, err = loadAWSConfigWithMFA(ctx, selectedProfile)
+		if err != nil {
+			return aws.Config{}, nil, fmt.Errorf("failed to load AWS config with MFA: %w", err)
 		}
+		ecsClient = ecs.NewFromConfig(cfg)
+		err = fn(ecsClient)
 	}
-
-	// Select service
-	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
 	if err != nil {
-		return fmt.Errorf("failed to select service: %w", err)
+		return aws.Config{}, nil, err
 	}
 
-	// Select task
-	selectedTask, err := selectTask(ctx, ecsClient, selectedCluster, selectedService)
+	return cfg, ecsClient, nil
+}
+
+// isAccessDenied reports whether err looks like a denial that MFA
+// authentication may resolve.
+func isAccessDenied(err error) bool {
+	return strings.Contains(err.Error(), "explicit deny") || strings.Contains(err.Error(), "AccessDenied")
+}
+
+// listClustersWithAuth selects the AWS profile and returns the clusters
+// matching --cluster (all clusters when it is not given), retrying with MFA
+// authentication when access is denied.
+func listClustersWithAuth(ctx context.Context) (aws.Config, *ecs.Client, []string, error) {
+	var clusterNames []string
+	cfg, ecsClient, err := loadConfigWithAuth(ctx, func(client *ecs.Client) error {
+		// An exact --cluster needs no listing
+		if cluster != "" && !isNamePattern(cluster) {
+			clusterNames = []string{cluster}
+			return nil
+		}
+
+		var err error
+		clusterNames, err = listClusterNames(ctx, client)
+		if err != nil {
+			return fmt.Errorf("failed to list clusters: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to select task: %w", err)
+		return aws.Config{}, nil, nil, err
 	}
 
-	// Execute command
-	return executeCommand(ctx, cfg, selectedCluster, selectedTask, selectedService)
+	clusterNames, err = filterNames(cluster, clusterNames)
+	if err != nil {
+		return aws.Config{}, nil, nil, err
+	}
+	return cfg, ecsClient, clusterNames, nil
 }
 
 func selectProfile() (string, error) {
@@ -171,8 +559,9 @@ func selectProfile() (string, error) {
 	}
 
 	prompt := promptui.Select{
-		Label: "Select AWS Profile",
-		Items: profiles,
+		Label:  "Select AWS Profile",
+		Stdout: os.Stderr,
+		Items:  profiles,
 	}
 
 	_, result, err := prompt.Run()
@@ -277,7 +666,8 @@ func loadAWSConfigWithMFA(ctx context.Context, profile string) (aws.Config, erro
 
 	// Get MFA code
 	prompt := promptui.Prompt{
-		Label: "Enter MFA Code",
+		Label:  "Enter MFA Code",
+		Stdout: os.Stderr,
 	}
 	mfaCode, err := prompt.Run()
 	if err != nil {
@@ -316,18 +706,20 @@ func selectMFADevice(ctx context.Context, cfg aws.Config) (string, error) {
 	listOutput, err := iamClient.ListMFADevices(ctx, &iam.ListMFADevicesInput{})
 	if err != nil {
 		// If listing fails, fall back to manual entry
-		fmt.Printf("Unable to list MFA devices: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Unable to list MFA devices: %v\n", err)
 		mfaPrompt := promptui.Prompt{
-			Label: "Enter MFA Device ARN (e.g., arn:aws:iam::123456789012:mfa/username)",
+			Label:  "Enter MFA Device ARN (e.g., arn:aws:iam::123456789012:mfa/username)",
+			Stdout: os.Stderr,
 		}
 		return mfaPrompt.Run()
 	}
 	
 	if len(listOutput.MFADevices) == 0 {
 		// No MFA devices found, ask for manual entry
-		fmt.Println("No MFA devices found for the current user.")
+		fmt.Fprintln(os.Stderr, "No MFA devices found for the current user.")
 		mfaPrompt := promptui.Prompt{
-			Label: "Enter MFA Device ARN (e.g., arn:aws:iam::123456789012:mfa/username)",
+			Label:  "Enter MFA Device ARN (e.g., arn:aws:iam::123456789012:mfa/username)",
+			Stdout: os.Stderr,
 		}
 		return mfaPrompt.Run()
 	}
@@ -374,14 +766,15 @@ func selectMFADevice(ctx context.Context, cfg aws.Config) (string, error) {
 	
 	// If only one device, use it automatically
 	if len(deviceItems) == 1 {
-		fmt.Printf("Using MFA device: %s\n", deviceItems[0].Label)
+		fmt.Fprintf(os.Stderr, "Using MFA device: %s\n", deviceItems[0].Label)
 		return deviceItems[0].SerialNumber, nil
 	}
 	
 	// Multiple devices, let user choose
 	prompt := promptui.Select{
-		Label: "Select MFA Device",
-		Items: deviceLabels,
+		Label:  "Select MFA Device",
+		Stdout: os.Stderr,
+		Items:  deviceLabels,
 	}
 	
 	index, _, err := prompt.Run()
@@ -393,32 +786,37 @@ func selectMFADevice(ctx context.Context, cfg aws.Config) (string, error) {
 }
 
 func selectCluster(ctx context.Context, client *ecs.Client) (string, error) {
-	if cluster != "" {
+	if cluster != "" && !isNamePattern(cluster) {
 		return cluster, nil
 	}
 
 	// List clusters
-	listOutput, err := client.ListClusters(ctx, &ecs.ListClustersInput{})
+	clusterNames, err := listClusterNames(ctx, client)
 	if err != nil {
 		return "", err
 	}
 
-	if len(listOutput.ClusterArns) == 0 {
+	if len(clusterNames) == 0 {
 		return "", fmt.Errorf("no clusters found")
 	}
 
-	// Extract cluster names
-	var clusterNames []string
-	for _, arn := range listOutput.ClusterArns {
-		parts := strings.Split(arn, "/")
-		if len(parts) > 0 {
-			clusterNames = append(clusterNames, parts[len(parts)-1])
-		}
+	// Narrow down candidates when --cluster is a glob or regex
+	clusterNames, err = filterNames(cluster, clusterNames)
+	if err != nil {
+		return "", err
+	}
+	if len(clusterNames) == 0 {
+		return "", fmt.Errorf("no clusters match %s", cluster)
+	}
+	if cluster != "" && len(clusterNames) == 1 {
+		fmt.Fprintf(os.Stderr, "Using cluster: %s\n", clusterNames[0])
+		return clusterNames[0], nil
 	}
 
 	prompt := promptui.Select{
-		Label: "Select ECS Cluster",
-		Items: clusterNames,
+		Label:  "Select ECS Cluster",
+		Stdout: os.Stderr,
+		Items:  clusterNames,
 	}
 
 	_, result, err := prompt.Run()
@@ -429,25 +827,23 @@ func selectCluster(ctx context.Context, client *ecs.Client) (string, error) {
 	return result, nil
 }
 
-func selectService(ctx context.Context, client *ecs.Client, clusterName string) (string, error) {
-	if service != "" {
-		return service, nil
-	}
-
-	// List services
-	var serviceArns []string
+// listClusterNames returns the names of all clusters.
+func listClusterNames(ctx context.Context, client *ecs.Client) ([]string, error) {
+	var clusterNames []string
 	var nextToken *string
 
 	for {
-		listOutput, err := client.ListServices(ctx, &ecs.ListServicesInput{
-			Cluster:   aws.String(clusterName),
+		listOutput, err := client.ListClusters(ctx, &ecs.ListClustersInput{
 			NextToken: nextToken,
 		})
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
-		serviceArns = append(serviceArns, listOutput.ServiceArns...)
+		for _, arn := range listOutput.ClusterArns {
+			parts := strings.Split(arn, "/")
+			clusterNames = append(clusterNames, parts[len(parts)-1])
+		}
 
 		if listOutput.NextToken == nil {
 			break
@@ -455,22 +851,41 @@ func selectService(ctx context.Context, client *ecs.Client, clusterName string)
 		nextToken = listOutput.NextToken
 	}
 
-	if len(serviceArns) == 0 {
+	return clusterNames, nil
+}
+
+func selectService(ctx context.Context, client *ecs.Client, clusterName string) (string, error) {
+	if service != "" && !isNamePattern(service) {
+		return service, nil
+	}
+
+	// List services
+	serviceNames, err := listServiceNames(ctx, client, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	if len(serviceNames) == 0 {
 		return "", fmt.Errorf("no services found in cluster %s", clusterName)
 	}
 
-	// Extract service names
-	var serviceNames []string
-	for _, arn := range serviceArns {
-		parts := strings.Split(arn, "/")
-		if len(parts) > 0 {
-			serviceNames = append(serviceNames, parts[len(parts)-1])
-		}
+	// Narrow down candidates when --service is a glob or regex
+	serviceNames, err = filterNames(service, serviceNames)
+	if err != nil {
+		return "", err
+	}
+	if len(serviceNames) == 0 {
+		return "", fmt.Errorf("no services in cluster %s match %s", clusterName, service)
+	}
+	if service != "" && len(serviceNames) == 1 {
+		fmt.Fprintf(os.Stderr, "Using service: %s\n", serviceNames[0])
+		return serviceNames[0], nil
 	}
 
 	prompt := promptui.Select{
-		Label: "Select ECS Service",
-		Items: serviceNames,
+		Label:  "Select ECS Service",
+		Stdout: os.Stderr,
+		Items:  serviceNames,
 	}
 
 	_, result, err := prompt.Run()
@@ -481,31 +896,52 @@ func selectService(ctx context.Context, client *ecs.Client, clusterName string)
 	return result, nil
 }
 
-func selectTask(ctx context.Context, client *ecs.Client, clusterName, serviceName string) (string, error) {
+// listServiceNames returns the names of all services in a cluster.
+func listServiceNames(ctx context.Context, client *ecs.Client, clusterName string) ([]string, error) {
+	var serviceNames []string
+	var nextToken *string
+
+	for {
+		listOutput, err := client.ListServices(ctx, &ecs.ListServicesInput{
+			Cluster:   aws.String(clusterName),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, arn := range listOutput.ServiceArns {
+			parts := strings.Split(arn, "/")
+			serviceNames = append(serviceNames, parts[len(parts)-1])
+		}
+
+		if listOutput.NextToken == nil {
+			break
+		}
+		nextToken = listOutput.NextToken
+	}
+
+	return serviceNames, nil
+}
+
+func selectTask(ctx context.Context, cfg aws.Config, client *ecs.Client, clusterName, serviceName string) (string, error) {
 	if task != "" {
 		return task, nil
 	}
 
-	// List tasks for the service
-	listOutput, err := client.ListTasks(ctx, &ecs.ListTasksInput{
-		Cluster:     aws.String(clusterName),
-		ServiceName: aws.String(serviceName),
-	})
+	// List and describe tasks for the service
+	tasks, err := describeServiceTasks(ctx, client, clusterName, serviceName)
 	if err != nil {
 		return "", err
 	}
 
-	if len(listOutput.TaskArns) == 0 {
-		return "", fmt.Errorf("no tasks found for service %s", serviceName)
-	}
-
-	// Describe tasks to get more details
-	describeOutput, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
-		Cluster: aws.String(clusterName),
-		Tasks:   listOutput.TaskArns,
-	})
-	if err != nil {
-		return "", err
+	// Show recent resource usage with --metrics
+	var usageLabels map[string]string
+	if pickerMetrics && len(tasks) > 0 {
+		usageLabels, err = taskUsageLabels(ctx, cfg, clusterName, tasks)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
 	}
 
 	// Create task items with more info
@@ -516,14 +952,8 @@ func selectTask(ctx context.Context, client *ecs.Client, clusterName, serviceNam
 	}
 
 	var taskItems []taskItem
-	for _, task := range describeOutput.Tasks {
-		taskID := ""
-		if task.TaskArn != nil {
-			parts := strings.Split(*task.TaskArn, "/")
-			if len(parts) > 0 {
-				taskID = parts[len(parts)-1]
-			}
-		}
+	for _, task := range tasks {
+		taskID := taskIDFromArn(aws.ToString(task.TaskArn))
 
 		status := ""
 		if task.LastStatus != nil {
@@ -531,6 +961,9 @@ func selectTask(ctx context.Context, client *ecs.Client, clusterName, serviceNam
 		}
 
 		label := fmt.Sprintf("%s (%s)", taskID, status)
+		if usage, ok := usageLabels[taskID]; ok {
+			label += "  " + usage
+		}
 		taskItems = append(taskItems, taskItem{
 			ID:     taskID,
 			Status: status,
@@ -547,11 +980,16 @@ func selectTask(ctx context.Context, client *ecs.Client, clusterName, serviceNam
 	}
 
 	if len(runningTasks) == 0 {
-		// No running tasks, ask if user wants to start a new one
-		fmt.Printf("No running tasks found for service %s.\n", serviceName)
+		// No running tasks, explain why and ask if user wants to start a new one
+		fmt.Fprintf(os.Stderr, "No running tasks found for service %s.\n\n", serviceName)
+		if err := printServiceEvents(ctx, os.Stderr, client, clusterName, serviceName, 5, 3); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get service events: %v\n", err)
+		}
+		fmt.Fprintln(os.Stderr)
 		
 		prompt := promptui.Prompt{
 			Label:     "Would you like to start a new task",
+			Stdout:    os.Stderr,
 			IsConfirm: true,
 		}
 		
@@ -561,7 +999,7 @@ func selectTask(ctx context.Context, client *ecs.Client, clusterName, serviceNam
 		}
 		
 		// Start a new task
-		fmt.Println("Starting a new task...")
+		fmt.Fprintln(os.Stderr, "Starting a new task...")
 		newTaskID, err := startNewTask(ctx, client, clusterName, serviceName)
 		if err != nil {
 			return "", fmt.Errorf("failed to start new task: %w", err)
@@ -571,8 +1009,9 @@ func selectTask(ctx context.Context, client *ecs.Client, clusterName, serviceNam
 	}
 
 	prompt := promptui.Select{
-		Label: "Select ECS Task",
-		Items: runningTasks,
+		Label:  "Select ECS Task",
+		Stdout: os.Stderr,
+		Items:  runningTasks,
 	}
 
 	_, result, err := prompt.Run()
@@ -590,6 +1029,61 @@ func selectTask(ctx context.Context, client *ecs.Client, clusterName, serviceNam
 	return "", fmt.Errorf("task not found")
 }
 
+// describeServiceTasks lists the tasks of a service and returns their
+// details.
+func describeServiceTasks(ctx context.Context, client *ecs.Client, clusterName, serviceName string) ([]types.Task, error) {
+	var taskArns []string
+	var nextToken *string
+
+	for {
+		listOutput, err := client.ListTasks(ctx, &ecs.ListTasksInput{
+			Cluster:     aws.String(clusterName),
+			ServiceName: aws.String(serviceName),
+			NextToken:   nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		taskArns = append(taskArns, listOutput.TaskArns...)
+
+		if listOutput.NextToken == nil {
+			break
+		}
+		nextToken = listOutput.NextToken
+	}
+
+	return describeTasks(ctx, client, clusterName, taskArns)
+}
+
+// describeTasks describes tasks in batches of the API limit.
+func describeTasks(ctx context.Context, client *ecs.Client, clusterName string, taskArns []string) ([]types.Task, error) {
+	var tasks []types.Task
+	for start := 0; start < len(taskArns); start += 100 {
+		end := start + 100
+		if end > len(taskArns) {
+			end = len(taskArns)
+		}
+
+		describeOutput, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(clusterName),
+			Tasks:   taskArns[start:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, describeOutput.Tasks...)
+	}
+
+	return tasks, nil
+}
+
+// taskIDFromArn returns the task ID part of a task ARN.
+func taskIDFromArn(arn string) string {
+	parts := strings.Split(arn, "/")
+	return parts[len(parts)-1]
+}
+
 func selectContainer(ctx context.Context, client *ecs.Client, clusterName, taskID string) (string, error) {
 	// Describe task to get container details
 	describeOutput, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
@@ -625,8 +1119,9 @@ func selectContainer(ctx context.Context, client *ecs.Client, clusterName, taskI
 
 	// Multiple containers, let user choose
 	prompt := promptui.Select{
-		Label: "Select Container",
-		Items: containerNames,
+		Label:  "Select Container",
+		Stdout: os.Stderr,
+		Items:  containerNames,
 	}
 
 	_, result, err := prompt.Run()
@@ -638,10 +1133,11 @@ func selectContainer(ctx context.Context, client *ecs.Client, clusterName, taskI
 }
 
 func executeCommand(ctx context.Context, cfg aws.Config, clusterName, taskID, serviceName string) error {
+	ecsClient := ecs.NewFromConfig(cfg)
+
 	// Select container if not specified
 	selectedContainer := container
 	if selectedContainer == "" {
-		ecsClient := ecs.NewFromConfig(cfg)
 		var err error
 		selectedContainer, err = selectContainer(ctx, ecsClient, clusterName, taskID)
 		if err != nil {
@@ -649,47 +1145,44 @@ func executeCommand(ctx context.Context, cfg aws.Config, clusterName, taskID, se
 		}
 	}
 
-	// Build aws ecs execute-command
-	args := []string{
-		"ecs", "execute-command",
-		"--cluster", clusterName,
-		"--task", taskID,
-		"--interactive",
-		"--command", command,
-	}
+	return execContainer(ctx, cfg, clusterName, serviceName, taskID, selectedContainer)
+}
 
-	// Add container if specified
-	if selectedContainer != "" {
-		args = append(args, "--container", selectedContainer)
-	}
+// execContainer opens an interactive session in a container.
+func execContainer(ctx context.Context, cfg aws.Config, clusterName, serviceName, taskID, selectedContainer string) error {
+	ecsClient := ecs.NewFromConfig(cfg)
 
-	// Add region if available
-	if cfg.Region != "" {
-		args = append(args, "--region", cfg.Region)
+	// Pick the shell
+	shell, err := resolveShell(ctx, cfg, clusterName, serviceName, taskID, selectedContainer)
+	if err != nil {
+		return err
 	}
 
-	// Use AWS CLI with the session credentials
-	cmd := exec.Command("aws", args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	// Set environment variables for AWS credentials
-	creds, err := cfg.Credentials.Retrieve(ctx)
+	// Record the session if requested
+	recorder, err := startSessionRecording(ctx, cfg, clusterName, serviceName, taskID, selectedContainer, shell)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve credentials: %w", err)
+		return err
 	}
-
-	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("AWS_ACCESS_KEY_ID=%s", creds.AccessKeyID),
-		fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%s", creds.SecretAccessKey),
-	)
-	if creds.SessionToken != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("AWS_SESSION_TOKEN=%s", creds.SessionToken))
+	if recorder != nil {
+		defer recorder.Close()
 	}
 
+	// Start the session through the ECS API
 	fmt.Printf("Executing command on task %s...\n", taskID)
-	return cmd.Run()
+	output, err := ecsClient.ExecuteCommand(ctx, &ecs.ExecuteCommandInput{
+		Cluster:     aws.String(clusterName),
+		Task:        aws.String(taskID),
+		Container:   aws.String(selectedContainer),
+		Command:     aws.String(shell),
+		Interactive: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute command: %w", err)
+	}
+
+	// Speak the Session Manager protocol directly instead of relying on
+	// the aws CLI and session-manager-plugin
+	return runInteractiveSession(ctx, output.Session, recorder)
 }
 
 // GitHub release structure
@@ -963,15 +1456,58 @@ func startNewTask(ctx context.Context, client *ecs.Client, clusterName, serviceN
 		return "", fmt.Errorf("no task definition found for service %s", serviceName)
 	}
 
-	// Run a new task
-	runTaskOutput, err := client.RunTask(ctx, &ecs.RunTaskInput{
+	// Let the user adjust the task before starting it
+	taskDef, _, err := describeTaskDefinition(ctx, client, aws.ToString(service.TaskDefinition))
+	if err != nil {
+		return "", err
+	}
+	overrides, err := editTaskOverrides(taskDef)
+	if err != nil {
+		return "", err
+	}
+
+	taskID, err := runServiceTask(ctx, client, clusterName, service, aws.ToString(service.TaskDefinition), overrides)
+	if taskID != "" {
+		trackStartedTask(client, clusterName, taskID)
+	}
+	return taskID, err
+}
+
+// runServiceTask runs taskDefinition the way the service would run it and
+// waits until the task is running. overrides may be nil.
+func runServiceTask(ctx context.Context, client *ecs.Client, clusterName string, service types.Service, taskDefinition string, overrides *types.TaskOverride) (string, error) {
+	input := &ecs.RunTaskInput{
 		Cluster:        aws.String(clusterName),
-		TaskDefinition: service.TaskDefinition,
-		LaunchType:     service.LaunchType,
+		TaskDefinition: aws.String(taskDefinition),
 		NetworkConfiguration: service.NetworkConfiguration,
 		PlatformVersion: service.PlatformVersion,
 		EnableExecuteCommand: true,
-	})
+		EnableECSManagedTags: service.EnableECSManagedTags,
+		Overrides:            overrides,
+		StartedBy:            aws.String(startedByMarker()),
+	}
+
+	// A capacity provider strategy and a launch type are mutually exclusive
+	if len(service.CapacityProviderStrategy) > 0 {
+		input.CapacityProviderStrategy = service.CapacityProviderStrategy
+	} else {
+		input.LaunchType = service.LaunchType
+	}
+
+	// Tag the task like the service tags its tasks
+	switch service.PropagateTags {
+	case types.PropagateTagsTaskDefinition:
+		input.PropagateTags = types.PropagateTagsTaskDefinition
+	case types.PropagateTagsService:
+		tags, err := serviceTags(ctx, client, aws.ToString(service.ServiceArn))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to get service tags: %v\n", err)
+		}
+		input.Tags = tags
+	}
+
+	// Run a new task
+	runTaskOutput, err := client.RunTask(ctx, input)
 	if err != nil {
 		return "", fmt.Errorf("failed to run task: %w", err)
 	}
@@ -989,8 +1525,8 @@ func startNewTask(ctx context.Context, client *ecs.Client, clusterName, serviceN
 		}
 	}
 
-	fmt.Printf("New task started: %s\n", taskID)
-	fmt.Println("Waiting for task to become running...")
+	fmt.Fprintf(os.Stderr, "New task started: %s\n", taskID)
+	fmt.Fprintln(os.Stderr, "Waiting for task to become running...")
 
 	// Wait for task to be in RUNNING state
 	waiter := ecs.NewTasksRunningWaiter(client)
@@ -1000,9 +1536,9 @@ func startNewTask(ctx context.Context, client *ecs.Client, clusterName, serviceN
 	}, 2*time.Minute)
 	
 	if err != nil {
-		return "", fmt.Errorf("task failed to start: %w", err)
+		return taskID, fmt.Errorf("task failed to start: %w", err)
 	}
 
-	fmt.Println("Task is now running!")
+	fmt.Fprintln(os.Stderr, "Task is now running!")
 	return taskID, nil
 }
\ No newline at end of file
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// isNamePattern reports whether a --cluster/--service value should be used
+// to filter the candidate list instead of being taken as an exact name.
+// Values wrapped in slashes are regular expressions, values containing glob
+// metacharacters are shell-style globs.
+func isNamePattern(value string) bool {
+	if isRegexPattern(value) {
+		return true
+	}
+	return strings.ContainsAny(value, "*?[")
+}
+
+func isRegexPattern(value string) bool {
+	return len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/")
+}
+
+// filterNames returns the names matching pattern. When pattern is empty or
+// an exact name, all names are returned unchanged.
+func filterNames(pattern string, names []string) ([]string, error) {
+	if !isNamePattern(pattern) {
+		return names, nil
+	}
+
+	var matched []string
+	if isRegexPattern(pattern) {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %w", pattern, err)
+		}
+		for _, name := range names {
+			if re.MatchString(name) {
+				matched = append(matched, name)
+			}
+		}
+		return matched, nil
+	}
+
+	for _, name := range names {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+		if ok {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
+
+// matchName reports whether name matches a --cluster/--service value: a
+// pattern, an exact name, or anything when the value is empty.
+func matchName(pattern, name string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+	if !isNamePattern(pattern) {
+		return pattern == name, nil
+	}
+	matched, err := filterNames(pattern, []string{name})
+	return len(matched) > 0, err
+}
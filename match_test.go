@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterNames(t *testing.T) {
+	names := []string{"api-prod", "api-staging", "worker-prod", "web"}
+	tests := []struct {
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{"", names, false},
+		{"web", names, false},
+		{"api-*", []string{"api-prod", "api-staging"}, false},
+		{"*-prod", []string{"api-prod", "worker-prod"}, false},
+		{"we?", []string{"web"}, false},
+		{"[aw]*-prod", []string{"api-prod", "worker-prod"}, false},
+		{"db-*", nil, false},
+		{"/prod$/", []string{"api-prod", "worker-prod"}, false},
+		{"/^(api|web)/", []string{"api-prod", "api-staging", "web"}, false},
+		{"/(/", nil, true},
+		{"[", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := filterNames(tt.pattern, names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("filterNames(%q) error = %v, wantErr %t", tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filterNames(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestMatchName(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+		wantErr bool
+	}{
+		{"", "anything", true, false},
+		{"web", "web", true, false},
+		{"web", "web-2", false, false},
+		{"web*", "web-2", true, false},
+		{"/^web-\\d$/", "web-2", true, false},
+		{"/^web-\\d$/", "web-22", false, false},
+		{"/", "/", true, false},
+		{"/[/", "x", false, true},
+	}
+	for _, tt := range tests {
+		got, err := matchName(tt.pattern, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("matchName(%q, %q) error = %v, wantErr %t", tt.pattern, tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("matchName(%q, %q) = %t, want %t", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	containerInsightsNamespace = "ECS/ContainerInsights"
+
+	// Metrics cover the last hour, drawn as a sparkline of 3 minute buckets
+	metricsWindow  = time.Hour
+	sparklineWidth = 20
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// usageTarget is a task, or a container when container is set, to get
+// metrics for. Limits are in CPU units and MiB, 0 when unknown.
+type usageTarget struct {
+	key         string
+	family      string
+	taskID      string
+	container   string
+	cpuLimit    float64
+	memoryLimit float64
+}
+
+// metricPoints is a metric series in time order.
+type metricPoints struct {
+	times  []time.Time
+	values []float64
+}
+
+func (p metricPoints) latest() (float64, bool) {
+	if len(p.values) == 0 {
+		return 0, false
+	}
+	return p.values[len(p.values)-1], true
+}
+
+// resourceUsage is the CPU (units) and memory (MiB) used by a target.
+type resourceUsage struct {
+	target usageTarget
+	cpu    metricPoints
+	memory metricPoints
+}
+
+// cpuPercent returns the latest CPU usage as a percentage of the limit.
+func (u resourceUsage) cpuPercent() (float64, bool) {
+	value, ok := u.cpu.latest()
+	if !ok || u.target.cpuLimit == 0 {
+		return 0, false
+	}
+	return value / u.target.cpuLimit * 100, true
+}
+
+// memoryPercent returns the latest memory usage as a percentage of the
+// limit.
+func (u resourceUsage) memoryPercent() (float64, bool) {
+	value, ok := u.memory.latest()
+	if !ok || u.target.memoryLimit == 0 {
+		return 0, false
+	}
+	return value / u.target.memoryLimit * 100, true
+}
+
+func runMetrics(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Select profile, cluster and service
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	tasks, err := describeServiceTasks(ctx, ecsClient, selectedCluster, selectedService)
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	var targets []usageTarget
+	for _, t := range tasks {
+		if aws.ToString(t.LastStatus) != "RUNNING" || !strings.HasPrefix(taskIDFromArn(aws.ToString(t.TaskArn)), task) {
+			continue
+		}
+		targets = append(targets, taskUsageTarget(t))
+		for _, c := range t.Containers {
+			if container == "" || aws.ToString(c.Name) == container {
+				targets = append(targets, containerUsageTarget(t, c))
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no running tasks found for service %s", selectedService)
+	}
+
+	usages, err := fetchUsage(ctx, cloudwatch.NewFromConfig(cfg), selectedCluster, targets)
+	if err != nil {
+		return err
+	}
+
+	l := listing{
+		columns:        []string{"task", "container", "cpu", "memory", "memorymib", "cputrend", "memorytrend", "cpulimit", "memorylimit"},
+		defaultColumns: []string{"task", "container", "cpu", "memory", "memorymib", "cputrend", "memorytrend"},
+	}
+	found := false
+	for _, target := range targets {
+		u := usages[target.key]
+		row := map[string]interface{}{
+			"task":        target.taskID,
+			"container":   target.container,
+			"cpu":         nil,
+			"memory":      nil,
+			"memorymib":   nil,
+			"cputrend":    sparkline(u.cpu, target.cpuLimit),
+			"memorytrend": sparkline(u.memory, target.memoryLimit),
+			"cpulimit":    target.cpuLimit,
+			"memorylimit": target.memoryLimit,
+		}
+		if value, ok := u.cpuPercent(); ok {
+			row["cpu"] = math.Round(value*10) / 10
+		}
+		if value, ok := u.memoryPercent(); ok {
+			row["memory"] = math.Round(value*10) / 10
+		}
+		if value, ok := u.memory.latest(); ok {
+			row["memorymib"] = math.Round(value)
+			found = true
+		}
+		l.rows = append(l.rows, row)
+	}
+
+	if err := printListing(os.Stdout, l, outputFormat, listColumns, listSort); err != nil {
+		return err
+	}
+	if !found {
+		fmt.Fprintln(os.Stderr, "No metrics found. Task and container metrics need Container Insights with enhanced observability on the cluster.")
+	}
+	return nil
+}
+
+func taskUsageTarget(t types.Task) usageTarget {
+	taskID := taskIDFromArn(aws.ToString(t.TaskArn))
+	return usageTarget{
+		key:         taskID,
+		family:      taskDefinitionFamily(aws.ToString(t.TaskDefinitionArn)),
+		taskID:      taskID,
+		cpuLimit:    parseLimit(t.Cpu),
+		memoryLimit: parseLimit(t.Memory),
+	}
+}
+
+// containerUsageTarget falls back to the task limits for containers
+// without their own.
+func containerUsageTarget(t types.Task, c types.Container) usageTarget {
+	target := taskUsageTarget(t)
+	target.container = aws.ToString(c.Name)
+	target.key += "/" + target.container
+	if limit := parseLimit(c.Cpu); limit > 0 {
+		target.cpuLimit = limit
+	}
+	if limit := parseLimit(c.Memory); limit > 0 {
+		target.memoryLimit = limit
+	} else if limit := parseLimit(c.MemoryReservation); limit > 0 {
+		target.memoryLimit = limit
+	}
+	return target
+}
+
+func parseLimit(value *string) float64 {
+	limit, err := strconv.ParseFloat(aws.ToString(value), 64)
+	if err != nil {
+		return 0
+	}
+	return limit
+}
+
+// taskDefinitionFamily returns the family of a task definition ARN.
+func taskDefinitionFamily(arn string) string {
+	name := taskDefinitionName(arn)
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// fetchUsage gets the CPU and memory used over the last hour by each target
+// from Container Insights, keyed by target key. Task and container level
+// metrics are only published with enhanced observability.
+func fetchUsage(ctx context.Context, client *cloudwatch.Client, clusterName string, targets []usageTarget) (map[string]resourceUsage, error) {
+	usages := make(map[string]resourceUsage, len(targets))
+	for _, target := range targets {
+		usages[target.key] = resourceUsage{target: target}
+	}
+
+	end := time.Now()
+	start := end.Add(-metricsWindow)
+
+	// GetMetricData takes up to 500 queries, two per target
+	for first := 0; first < len(targets); first += 250 {
+		last := first + 250
+		if last > len(targets) {
+			last = len(targets)
+		}
+
+		var queries []cwtypes.MetricDataQuery
+		for i := first; i < last; i++ {
+			queries = append(queries,
+				usageQuery(fmt.Sprintf("cpu%d", i), "CpuUtilized", clusterName, targets[i]),
+				usageQuery(fmt.Sprintf("memory%d", i), "MemoryUtilized", clusterName, targets[i]))
+		}
+
+		paginator := cloudwatch.NewGetMetricDataPaginator(client, &cloudwatch.GetMetricDataInput{
+			StartTime:         aws.Time(start),
+			EndTime:           aws.Time(end),
+			MetricDataQueries: queries,
+			ScanBy:            cwtypes.ScanByTimestampAscending,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get metrics: %w", err)
+			}
+			for _, result := range page.MetricDataResults {
+				id := aws.ToString(result.Id)
+				metric := "cpu"
+				if strings.HasPrefix(id, "memory") {
+					metric = "memory"
+				}
+				i, err := strconv.Atoi(strings.TrimPrefix(id, metric))
+				if err != nil || i >= len(targets) {
+					continue
+				}
+
+				u := usages[targets[i].key]
+				points := &u.cpu
+				if metric == "memory" {
+					points = &u.memory
+				}
+				points.times = append(points.times, result.Timestamps...)
+				points.values = append(points.values, result.Values...)
+				usages[targets[i].key] = u
+			}
+		}
+	}
+
+	for key, u := range usages {
+		sortPoints(&u.cpu)
+		sortPoints(&u.memory)
+		usages[key] = u
+	}
+	return usages, nil
+}
+
+func usageQuery(id, metricName, clusterName string, target usageTarget) cwtypes.MetricDataQuery {
+	dimensions := []cwtypes.Dimension{
+		{Name: aws.String("ClusterName"), Value: aws.String(clusterName)},
+		{Name: aws.String("TaskDefinitionFamily"), Value: aws.String(target.family)},
+		{Name: aws.String("TaskId"), Value: aws.String(target.taskID)},
+	}
+	if target.container != "" {
+		dimensions = append(dimensions, cwtypes.Dimension{Name: aws.String("ContainerName"), Value: aws.String(target.container)})
+	}
+
+	return cwtypes.MetricDataQuery{
+		Id: aws.String(id),
+		MetricStat: &cwtypes.MetricStat{
+			Metric: &cwtypes.Metric{
+				Namespace:  aws.String(containerInsightsNamespace),
+				MetricName: aws.String(metricName),
+				Dimensions: dimensions,
+			},
+			Period: aws.Int32(60),
+			Stat:   aws.String("Average"),
+		},
+	}
+}
+
+// sortPoints puts points from several pages back in time order.
+func sortPoints(p *metricPoints) {
+	sort.Sort(pointsByTime(*p))
+}
+
+type pointsByTime metricPoints
+
+func (p pointsByTime) Len() int           { return len(p.times) }
+func (p pointsByTime) Less(i, j int) bool { return p.times[i].Before(p.times[j]) }
+func (p pointsByTime) Swap(i, j int) {
+	p.times[i], p.times[j] = p.times[j], p.times[i]
+	p.values[i], p.values[j] = p.values[j], p.values[i]
+}
+
+// sparkline draws the average of each bucket of the metrics window, scaled
+// to limit, or to the highest value when the limit is unknown. Buckets
+// without data are blank.
+func sparkline(p metricPoints, limit float64) string {
+	if len(p.values) == 0 {
+		return ""
+	}
+
+	bucketSize := metricsWindow / sparklineWidth
+	start := time.Now().Add(-metricsWindow)
+	sums := make([]float64, sparklineWidth)
+	counts := make([]int, sparklineWidth)
+	for i, t := range p.times {
+		bucket := int(t.Sub(start) / bucketSize)
+		if bucket < 0 || bucket >= sparklineWidth {
+			continue
+		}
+		sums[bucket] += p.values[i]
+		counts[bucket]++
+	}
+
+	scale := limit
+	if scale == 0 {
+		for _, value := range p.values {
+			scale = math.Max(scale, value)
+		}
+	}
+
+	line := make([]rune, sparklineWidth)
+	for i := range line {
+		if counts[i] == 0 || scale == 0 {
+			line[i] = ' '
+			continue
+		}
+		level := int(sums[i] / float64(counts[i]) / scale * float64(len(sparkBlocks)))
+		if level >= len(sparkBlocks) {
+			level = len(sparkBlocks) - 1
+		}
+		if level < 0 {
+			level = 0
+		}
+		line[i] = sparkBlocks[level]
+	}
+	return string(line)
+}
+
+// taskUsageLabels returns a short CPU and memory summary for each running
+// task, keyed by task ID, for the task picker.
+func taskUsageLabels(ctx context.Context, cfg aws.Config, clusterName string, tasks []types.Task) (map[string]string, error) {
+	var targets []usageTarget
+	for _, t := range tasks {
+		targets = append(targets, taskUsageTarget(t))
+	}
+
+	usages, err := fetchUsage(ctx, cloudwatch.NewFromConfig(cfg), clusterName, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]string, len(usages))
+	for key, u := range usages {
+		cpu, memory := "-", "-"
+		if value, ok := u.cpuPercent(); ok {
+			cpu = fmt.Sprintf("%.0f%%", value)
+		}
+		if value, ok := u.memoryPercent(); ok {
+			memory = fmt.Sprintf("%.0f%%", value)
+		}
+		labels[key] = fmt.Sprintf("cpu %4s %s  mem %4s %s", cpu, sparkline(u.cpu, u.target.cpuLimit), memory, sparkline(u.memory, u.target.memoryLimit))
+	}
+	return labels, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// bucketPoints returns one point in the middle of each sparkline bucket that
+// has a value; nil values leave the bucket empty.
+func bucketPoints(values ...interface{}) metricPoints {
+	var p metricPoints
+	bucketSize := metricsWindow / sparklineWidth
+	start := time.Now().Add(-metricsWindow)
+	for i, v := range values {
+		value, ok := v.(float64)
+		if !ok {
+			continue
+		}
+		p.times = append(p.times, start.Add(time.Duration(i)*bucketSize+bucketSize/2))
+		p.values = append(p.values, value)
+	}
+	return p
+}
+
+func TestSparkline(t *testing.T) {
+	full := make([]interface{}, sparklineWidth)
+	for i := range full {
+		full[i] = 100.0
+	}
+
+	tests := []struct {
+		name   string
+		points metricPoints
+		limit  float64
+		want   string
+	}{
+		{"no data", metricPoints{}, 100, ""},
+		{"scaled to the limit", bucketPoints(0.0, 12.5, 25.0, 50.0, 99.0), 100, "▁▂▃▅█" + "               "},
+		{"at the limit", bucketPoints(full...), 100, "████████████████████"},
+		{"above the limit", bucketPoints(250.0), 100, "█" + "                   "},
+		{"gaps stay blank", bucketPoints(50.0, nil, 50.0), 100, "▅ ▅" + "                 "},
+		{"scaled to the maximum without a limit", bucketPoints(10.0, 20.0), 0, "▅█" + "                  "},
+		{"all zero without a limit", bucketPoints(0.0, 0.0), 0, "                    "},
+	}
+	for _, tt := range tests {
+		if got := sparkline(tt.points, tt.limit); got != tt.want {
+			t.Errorf("%s: sparkline() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSparklineAveragesBuckets(t *testing.T) {
+	start := time.Now().Add(-metricsWindow)
+	p := metricPoints{
+		times:  []time.Time{start.Add(time.Minute), start.Add(2 * time.Minute)},
+		values: []float64{0, 100},
+	}
+	// The average of 0 and 100 is half the limit
+	if got := []rune(sparkline(p, 100))[0]; got != '▅' {
+		t.Errorf("first bucket = %q, want %q", got, '▅')
+	}
+}
+
+func TestResourceUsagePercent(t *testing.T) {
+	u := resourceUsage{
+		target: usageTarget{cpuLimit: 512, memoryLimit: 1024},
+		cpu:    metricPoints{values: []float64{100, 256}},
+		memory: metricPoints{values: []float64{768}},
+	}
+	if got, ok := u.cpuPercent(); !ok || got != 50 {
+		t.Errorf("cpuPercent() = %v, %t, want 50, true", got, ok)
+	}
+	if got, ok := u.memoryPercent(); !ok || got != 75 {
+		t.Errorf("memoryPercent() = %v, %t, want 75, true", got, ok)
+	}
+
+	u.target.cpuLimit = 0
+	if _, ok := u.cpuPercent(); ok {
+		t.Error("cpuPercent() without a limit should not be known")
+	}
+	if _, ok := (resourceUsage{target: u.target}).memoryPercent(); ok {
+		t.Error("memoryPercent() without data should not be known")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value *string
+		want  float64
+	}{
+		{aws.String("512"), 512},
+		{aws.String("0.5"), 0.5},
+		{aws.String(""), 0},
+		{aws.String("1 vCPU"), 0},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		if got := parseLimit(tt.value); got != tt.want {
+			t.Errorf("parseLimit(%q) = %v, want %v", aws.ToString(tt.value), got, tt.want)
+		}
+	}
+}
+
+func TestTaskDefinitionFamily(t *testing.T) {
+	tests := []struct {
+		arn  string
+		want string
+	}{
+		{"arn:aws:ecs:ap-northeast-1:123456789012:task-definition/web:12", "web"},
+		{"web:3", "web"},
+		{"web", "web"},
+	}
+	for _, tt := range tests {
+		if got := taskDefinitionFamily(tt.arn); got != tt.want {
+			t.Errorf("taskDefinitionFamily(%q) = %q, want %q", tt.arn, got, tt.want)
+		}
+	}
+}
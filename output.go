@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for --output
+const (
+	outputTable = "table"
+	outputTSV   = "tsv"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// listing is a set of rows for machine or human readable output. Each row
+// maps a column name to its value.
+type listing struct {
+	columns        []string
+	defaultColumns []string
+	rows           []map[string]interface{}
+}
+
+// printListing prints the rows with the selected columns (comma separated,
+// "all", or the default columns when empty), sorted by sortColumn. A
+// leading "-" sorts in descending order.
+func printListing(w io.Writer, l listing, format, columnList, sortColumn string) error {
+	columns := l.defaultColumns
+	switch columnList {
+	case "":
+	case "all":
+		columns = l.columns
+	default:
+		columns = strings.Split(columnList, ",")
+		for i, column := range columns {
+			columns[i] = strings.ToLower(strings.TrimSpace(column))
+			if !containsString(l.columns, columns[i]) {
+				return fmt.Errorf("unknown column %q (available: %s)", column, strings.Join(l.columns, ", "))
+			}
+		}
+	}
+
+	rows := l.rows
+	if sortColumn != "" {
+		descending := strings.HasPrefix(sortColumn, "-")
+		sortColumn = strings.ToLower(strings.TrimPrefix(sortColumn, "-"))
+		if !containsString(l.columns, sortColumn) {
+			return fmt.Errorf("unknown sort column %q (available: %s)", sortColumn, strings.Join(l.columns, ", "))
+		}
+		rows = append([]map[string]interface{}{}, rows...)
+		sort.SliceStable(rows, func(i, j int) bool {
+			if descending {
+				return lessValue(rows[j][sortColumn], rows[i][sortColumn])
+			}
+			return lessValue(rows[i][sortColumn], rows[j][sortColumn])
+		})
+	}
+
+	// Keep only the selected columns
+	selected := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		selected[i] = make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			selected[i][column] = row[column]
+		}
+	}
+
+	switch format {
+	case outputTable, outputTSV:
+		var tw *tabwriter.Writer
+		out := w
+		if format == outputTable {
+			tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			out = tw
+			fmt.Fprintln(out, strings.ToUpper(strings.Join(columns, "\t")))
+		}
+		for _, row := range selected {
+			values := make([]string, len(columns))
+			for i, column := range columns {
+				values[i] = formatValue(row[column])
+				// Empty TSV fields stay empty so they read back as empty
+				if values[i] == "" && format == outputTable {
+					values[i] = "-"
+				}
+			}
+			fmt.Fprintln(out, strings.Join(values, "\t"))
+		}
+		if tw != nil {
+			return tw.Flush()
+		}
+		return nil
+	case outputJSON:
+		return printJSON(w, selected)
+	case outputYAML:
+		return printYAML(w, selected)
+	}
+	return fmt.Errorf("unknown output format %q (want table, tsv, json or yaml)", format)
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printYAML prints v as YAML with the same keys and key order as its JSON
+// form, which also keeps SDK types' internal fields out.
+func printYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle drops the JSON flow style and quoting so the output reads as
+// ordinary YAML.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func formatValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []string:
+		return strings.Join(value, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// lessValue compares numbers numerically and everything else as text.
+func lessValue(a, b interface{}) bool {
+	x, xErr := strconv.ParseFloat(fmt.Sprint(a), 64)
+	y, yErr := strconv.ParseFloat(fmt.Sprint(b), 64)
+	if xErr == nil && yErr == nil {
+		return x < y
+	}
+	return formatValue(a) < formatValue(b)
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
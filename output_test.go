@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testListing() listing {
+	return listing{
+		columns:        []string{"name", "status", "count", "tags"},
+		defaultColumns: []string{"name", "status", "count"},
+		rows: []map[string]interface{}{
+			{"name": "web", "status": "ACTIVE", "count": 10, "tags": []string{"a", "b"}},
+			{"name": "api", "status": "", "count": 2, "tags": []string{}},
+			{"name": "batch", "status": nil, "count": 0, "tags": nil},
+		},
+	}
+}
+
+func TestPrintListing(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		columns string
+		sort    string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "table with default columns",
+			format: outputTable,
+			want: "NAME   STATUS  COUNT\n" +
+				"web    ACTIVE  10\n" +
+				"api    -       2\n" +
+				"batch  -       0\n",
+		},
+		{
+			name:   "tsv keeps empty fields empty",
+			format: outputTSV,
+			want:   "web\tACTIVE\t10\napi\t\t2\nbatch\t\t0\n",
+		},
+		{
+			name:    "selected columns",
+			format:  outputTSV,
+			columns: "Name, tags",
+			want:    "web\ta,b\napi\t\nbatch\t\n",
+		},
+		{
+			name:    "numeric sort",
+			format:  outputTSV,
+			columns: "name,count",
+			sort:    "count",
+			want:    "batch\t0\napi\t2\nweb\t10\n",
+		},
+		{
+			name:    "descending sort",
+			format:  outputTSV,
+			columns: "name",
+			sort:    "-name",
+			want:    "web\nbatch\napi\n",
+		},
+		{
+			name:    "json",
+			format:  outputJSON,
+			columns: "name,count",
+			sort:    "name",
+			want: `[
+  {
+    "count": 2,
+    "name": "api"
+  },
+  {
+    "count": 0,
+    "name": "batch"
+  },
+  {
+    "count": 10,
+    "name": "web"
+  }
+]
+`,
+		},
+		{
+			name:    "yaml",
+			format:  outputYAML,
+			columns: "name,tags",
+			sort:    "-count",
+			want: `- name: web
+  tags:
+    - a
+    - b
+- name: api
+  tags: []
+- name: batch
+  tags: null
+`,
+		},
+		{name: "unknown column", format: outputTable, columns: "name,nope", wantErr: "unknown column"},
+		{name: "unknown sort column", format: outputTable, sort: "nope", wantErr: "unknown sort column"},
+		{name: "unknown format", format: "xml", wantErr: "unknown output format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := printListing(&out, testListing(), tt.format, tt.columns, tt.sort)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("printListing() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("printListing() error = %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("printListing() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"", ""},
+		{"x", "x"},
+		{[]string{}, ""},
+		{[]string{"a", "b"}, "a,b"},
+		{int32(3), "3"},
+		{true, "true"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.value); got != tt.want {
+			t.Errorf("formatValue(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
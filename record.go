@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+// asciicastHeader is the first line of an asciicast v2 file. Session
+// metadata is kept under the "ecsy" key, which players ignore.
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Ecsy      map[string]string `json:"ecsy,omitempty"`
+}
+
+// asciicastRecorder writes terminal events to an asciicast v2 file. It is
+// an io.Writer for session output.
+type asciicastRecorder struct {
+	mu          sync.Mutex
+	file        *os.File
+	writer      *bufio.Writer
+	start       time.Time
+	recordInput bool
+	pending     map[string][]byte
+}
+
+func newAsciicastRecorder(path string, header asciicastHeader, recordInput bool) (*asciicastRecorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &asciicastRecorder{
+		file:        file,
+		writer:      bufio.NewWriter(file),
+		start:       time.Now(),
+		recordInput: recordInput,
+		pending:     make(map[string][]byte),
+	}
+	header.Version = 2
+	header.Timestamp = r.start.Unix()
+	line, err := json.Marshal(header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	r.writer.Write(append(line, '\n'))
+
+	return r, nil
+}
+
+// Write records session output.
+func (r *asciicastRecorder) Write(p []byte) (int, error) {
+	r.event("o", p)
+	return len(p), nil
+}
+
+// Input returns a writer that records keyboard input, or discards it when
+// input recording is disabled.
+func (r *asciicastRecorder) Input() *asciicastInput {
+	return &asciicastInput{r}
+}
+
+// Resize records a terminal size change.
+func (r *asciicastRecorder) Resize(cols, rows int) {
+	r.event("r", []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+// Close flushes and closes the recording.
+func (r *asciicastRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+func (r *asciicastRecorder) event(kind string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Events must be valid UTF-8, so hold back a rune split across writes
+	data = append(r.pending[kind], data...)
+	cut := incompleteRuneStart(data)
+	r.pending[kind] = append([]byte(nil), data[cut:]...)
+	data = data[:cut]
+	if len(data) == 0 {
+		return
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	line, err := json.Marshal([]interface{}{elapsed, kind, string(data)})
+	if err != nil {
+		return
+	}
+	r.writer.Write(append(line, '\n'))
+}
+
+type asciicastInput struct {
+	r *asciicastRecorder
+}
+
+func (i *asciicastInput) Write(p []byte) (int, error) {
+	if i.r.recordInput {
+		i.r.event("i", p)
+	}
+	return len(p), nil
+}
+
+// incompleteRuneStart returns the index where a trailing incomplete UTF-8
+// sequence starts, or len(data) if there is none.
+func incompleteRuneStart(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// startSessionRecording opens a recording for an interactive session when
+// --record is given or the profile's record setting is "always". It returns
+// nil when the session is not recorded.
+func startSessionRecording(ctx context.Context, cfg aws.Config, clusterName, serviceName, taskID, containerName, shell string) (*asciicastRecorder, error) {
+	settings, err := loadEcsyConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	path := recordFile
+	recordInput := recordInputFlag || settings.profileSetting(profile, "record_input") == "true"
+	if path == "" {
+		if settings.profileSetting(profile, "record") != "always" {
+			return nil, nil
+		}
+		dir := settings.profileSetting(profile, "record_dir")
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(ecsyConfigPath()), "recordings")
+		}
+		name := fmt.Sprintf("%s-%s-%s.cast", time.Now().Format("20060102-150405"), clusterName, shortTaskID(taskID))
+		path = filepath.Join(expandHome(dir), sanitizeFileName(name))
+	}
+
+	header := asciicastHeader{
+		Title: fmt.Sprintf("ecsy %s/%s/%s", clusterName, taskID, containerName),
+		Env: map[string]string{
+			"SHELL": shell,
+			"TERM":  os.Getenv("TERM"),
+		},
+		Ecsy: map[string]string{
+			"profile":   profile,
+			"region":    cfg.Region,
+			"cluster":   clusterName,
+			"service":   serviceName,
+			"task":      taskID,
+			"container": containerName,
+			"command":   shell,
+		},
+	}
+	if cols, rows, ok := terminalSize(); ok {
+		header.Width, header.Height = cols, rows
+	} else {
+		header.Width, header.Height = 80, 24
+	}
+
+	// Record who opened the session
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err == nil {
+		header.Ecsy["account"] = aws.ToString(identity.Account)
+		header.Ecsy["identity"] = aws.ToString(identity.Arn)
+	}
+
+	recorder, err := newAsciicastRecorder(path, header, recordInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+	fmt.Printf("Recording session to %s\n", path)
+	return recorder, nil
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func sanitizeFileName(name string) string {
+	return unsafeFileNameChars.ReplaceAllString(name, "_")
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	if replaySpeed <= 0 {
+		return fmt.Errorf("--speed must be positive")
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		return fmt.Errorf("empty recording: %s", args[0])
+	}
+	var header asciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return fmt.Errorf("invalid recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	if len(header.Ecsy) > 0 {
+		fmt.Fprintf(os.Stderr, "Recorded %s by %s (%s/%s, container %s)\n",
+			time.Unix(header.Timestamp, 0).Format(time.RFC3339), header.Ecsy["identity"],
+			header.Ecsy["cluster"], header.Ecsy["task"], header.Ecsy["container"])
+	}
+
+	var last float64
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+			continue
+		}
+		at, _ := event[0].(float64)
+		kind, _ := event[1].(string)
+		data, _ := event[2].(string)
+		if kind != "o" {
+			last = at
+			continue
+		}
+
+		// Wait for the recorded delay, capping long idle periods
+		delay := time.Duration((at - last) / replaySpeed * float64(time.Second))
+		if replayMaxIdle > 0 && delay > replayMaxIdle {
+			delay = replayMaxIdle
+		}
+		time.Sleep(delay)
+		last = at
+
+		os.Stdout.WriteString(data)
+	}
+	return scanner.Err()
+}
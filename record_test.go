@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readRecording(t *testing.T, path string) (asciicastHeader, [][]interface{}) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatal("recording is empty")
+	}
+	var header asciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("invalid header: %v", err)
+	}
+	var events [][]interface{}
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return header, events
+}
+
+func TestAsciicastRecorder(t *testing.T) {
+	tests := []struct {
+		name        string
+		recordInput bool
+		write       func(r *asciicastRecorder)
+		want        [][2]string
+	}{
+		{
+			name: "output and resize",
+			write: func(r *asciicastRecorder) {
+				r.Write([]byte("$ ls\r\n"))
+				r.Resize(120, 40)
+				r.Write([]byte("a.txt\r\n"))
+			},
+			want: [][2]string{{"o", "$ ls\r\n"}, {"r", "120x40"}, {"o", "a.txt\r\n"}},
+		},
+		{
+			name: "input is dropped by default",
+			write: func(r *asciicastRecorder) {
+				r.Input().Write([]byte("secret\r"))
+				r.Write([]byte("ok"))
+			},
+			want: [][2]string{{"o", "ok"}},
+		},
+		{
+			name:        "input is recorded when enabled",
+			recordInput: true,
+			write: func(r *asciicastRecorder) {
+				r.Input().Write([]byte("ls\r"))
+			},
+			want: [][2]string{{"i", "ls\r"}},
+		},
+		{
+			name: "rune split across writes",
+			write: func(r *asciicastRecorder) {
+				r.Write([]byte("あ")[:1])
+				r.Write([]byte("あ")[1:])
+				r.Write([]byte("い"))
+			},
+			want: [][2]string{{"o", "あ"}, {"o", "い"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sub", "session.cast")
+			r, err := newAsciicastRecorder(path, asciicastHeader{
+				Width:  80,
+				Height: 24,
+				Title:  "test",
+				Ecsy:   map[string]string{"cluster": "c1"},
+			}, tt.recordInput)
+			if err != nil {
+				t.Fatalf("newAsciicastRecorder() error = %v", err)
+			}
+			tt.write(r)
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			header, events := readRecording(t, path)
+			if header.Version != 2 || header.Width != 80 || header.Height != 24 || header.Timestamp == 0 || header.Ecsy["cluster"] != "c1" {
+				t.Errorf("header = %+v", header)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events %v, want %v", len(events), events, tt.want)
+			}
+			last := 0.0
+			for i, event := range events {
+				at, _ := event[0].(float64)
+				if at < last {
+					t.Errorf("event %d goes back in time: %v", i, event)
+				}
+				last = at
+				if event[1] != tt.want[i][0] || event[2] != tt.want[i][1] {
+					t.Errorf("event %d = %v, want %v", i, event[1:], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIncompleteRuneStart(t *testing.T) {
+	a := []byte("あ")
+	emoji := []byte("😀")
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"empty", nil, 0},
+		{"ascii", []byte("abc"), 3},
+		{"complete rune", a, 3},
+		{"one byte of three", a[:1], 0},
+		{"two bytes of three", append([]byte("x"), a[:2]...), 1},
+		{"three bytes of four", emoji[:3], 0},
+		{"invalid continuation bytes", []byte{'x', 0x80, 0x80}, 3},
+	}
+	for _, tt := range tests {
+		if got := incompleteRuneStart(tt.data); got != tt.want {
+			t.Errorf("%s: incompleteRuneStart(% x) = %d, want %d", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	if got := sanitizeFileName("20240101-120000-my cluster/1-abc.cast"); got != "20240101-120000-my_cluster_1-abc.cast" {
+		t.Errorf("sanitizeFileName() = %q", got)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+func runRestart(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Select profile, cluster and service
+	_, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	describeOutput, err := ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(selectedCluster),
+		Services: []string{selectedService},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	if len(describeOutput.Services) == 0 {
+		return fmt.Errorf("service not found: %s", selectedService)
+	}
+	s := describeOutput.Services[0]
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Restart service %s in %s, replacing its %d running task(s)", selectedService, selectedCluster, s.RunningCount),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	// Replace the tasks with a new deployment of the same task definition
+	started := time.Now()
+	updateOutput, err := ecsClient.UpdateService(ctx, &ecs.UpdateServiceInput{
+		Cluster:            aws.String(selectedCluster),
+		Service:            aws.String(selectedService),
+		ForceNewDeployment: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restart service: %w", err)
+	}
+
+	deploymentID := primaryDeploymentID(updateOutput.Service)
+	fmt.Printf("Started deployment %s\n", deploymentID)
+	return watchDeployment(ctx, ecsClient, selectedCluster, selectedService, deploymentID, deploymentStart(updateOutput.Service, started))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if rollbackLimit < 1 {
+		return fmt.Errorf("--limit must be at least 1")
+	}
+
+	revision := 0
+	if len(args) > 0 {
+		var err error
+		revision, err = strconv.Atoi(args[0])
+		if err != nil || revision <= 0 {
+			return fmt.Errorf("invalid revision %q", args[0])
+		}
+	}
+
+	// Select profile, cluster and service
+	_, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	describeOutput, err := ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(selectedCluster),
+		Services: []string{selectedService},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	if len(describeOutput.Services) == 0 {
+		return fmt.Errorf("service not found: %s", selectedService)
+	}
+	currentTaskDef := aws.ToString(describeOutput.Services[0].TaskDefinition)
+	family := taskDefinitionFamily(currentTaskDef)
+	fmt.Printf("Current task definition: %s\n", taskDefinitionName(currentTaskDef))
+
+	var target string
+	if revision > 0 {
+		target = fmt.Sprintf("%s:%d", family, revision)
+		if target == taskDefinitionName(currentTaskDef) {
+			return fmt.Errorf("service %s already uses %s", selectedService, target)
+		}
+	} else {
+		target, err = selectRevision(ctx, ecsClient, currentTaskDef)
+		if err != nil {
+			return fmt.Errorf("failed to select revision: %w", err)
+		}
+	}
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Roll back service %s in %s to %s", selectedService, selectedCluster, taskDefinitionName(target)),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	return updateServiceTaskDefinition(ctx, ecsClient, selectedCluster, selectedService, target)
+}
+
+// selectRevision lets the user pick another active revision of the task
+// definition family, newest first, with the cursor on the revision before
+// the current one.
+func selectRevision(ctx context.Context, client *ecs.Client, currentTaskDef string) (string, error) {
+	family := taskDefinitionFamily(currentTaskDef)
+	currentRevision := taskDefinitionRevision(currentTaskDef)
+
+	var arns []string
+	paginator := ecs.NewListTaskDefinitionsPaginator(client, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Status:       types.TaskDefinitionStatusActive,
+		Sort:         types.SortOrderDesc,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list task definitions: %w", err)
+		}
+		for _, arn := range page.TaskDefinitionArns {
+			// The prefix also matches other families
+			if taskDefinitionFamily(arn) == family && arn != currentTaskDef {
+				arns = append(arns, arn)
+			}
+		}
+	}
+	if len(arns) == 0 {
+		return "", fmt.Errorf("no other active revisions of %s", family)
+	}
+
+	// Default to the newest revision older than the current one, and list
+	// --limit revisions around it
+	previous := 0
+	for i, arn := range arns {
+		if taskDefinitionRevision(arn) < currentRevision {
+			previous = i
+			break
+		}
+	}
+	start := 0
+	if previous >= rollbackLimit {
+		start = previous - rollbackLimit/2
+	}
+	end := start + rollbackLimit
+	if end > len(arns) {
+		end = len(arns)
+	}
+	arns = arns[start:end]
+	cursor := previous - start
+
+	items := make([]string, len(arns))
+	for i, arn := range arns {
+		taskDef, _, err := describeTaskDefinition(ctx, client, arn)
+		if err != nil {
+			return "", err
+		}
+
+		var images []string
+		for _, definition := range taskDef.ContainerDefinitions {
+			images = append(images, fmt.Sprintf("%s=%s", aws.ToString(definition.Name), imageTag(aws.ToString(definition.Image))))
+		}
+		items[i] = fmt.Sprintf("%s  %s  %s", taskDefinitionName(arn), formatEventTime(taskDef.RegisteredAt), strings.Join(images, " "))
+	}
+
+	prompt := promptui.Select{
+		Label: "Select Task Definition Revision",
+		Items: items,
+		Size:  10,
+	}
+	index, _, err := prompt.RunCursorAt(cursor, cursor-9)
+	if err != nil {
+		return "", err
+	}
+	return arns[index], nil
+}
+
+// taskDefinitionRevision returns the revision of a task definition ARN, or 0.
+func taskDefinitionRevision(arn string) int {
+	name := taskDefinitionName(arn)
+	revision, err := strconv.Atoi(name[strings.LastIndex(name, ":")+1:])
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// imageTag shortens an image to its repository name and tag or digest.
+func imageTag(image string) string {
+	return image[strings.LastIndex(image, "/")+1:]
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestTaskDefinitionRevision(t *testing.T) {
+	tests := []struct {
+		arn  string
+		want int
+	}{
+		{"arn:aws:ecs:ap-northeast-1:123456789012:task-definition/web:12", 12},
+		{"web:3", 3},
+		{"web", 0},
+		{"web:latest", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := taskDefinitionRevision(tt.arn); got != tt.want {
+			t.Errorf("taskDefinitionRevision(%q) = %d, want %d", tt.arn, got, tt.want)
+		}
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"nginx:1.27", "nginx:1.27"},
+		{"123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/team/web:v2", "web:v2"},
+		{"registry.example.com:5000/web:v2", "web:v2"},
+		{"public.ecr.aws/nginx/nginx@sha256:0123abcd", "nginx@sha256:0123abcd"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := imageTag(tt.image); got != tt.want {
+			t.Errorf("imageTag(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
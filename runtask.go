@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/user"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/manifoldco/promptui"
+)
+
+// startedByPrefix marks tasks started by ecsy in their startedBy field.
+const startedByPrefix = "ecsy/"
+
+var unsafeStartedByChars = regexp.MustCompile(`[^A-Za-z0-9_/-]`)
+
+// startedByMarker returns the startedBy value for tasks ecsy starts,
+// ecsy/<local user>.
+func startedByMarker() string {
+	name := "unknown"
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		name = current.Username
+		// Drop the domain of Windows accounts
+		if i := strings.LastIndex(name, `\`); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+
+	marker := startedByPrefix + unsafeStartedByChars.ReplaceAllString(name, "_")
+	if len(marker) > 128 {
+		marker = marker[:128]
+	}
+	return marker
+}
+
+// serviceTags returns the tags of a service that can be set on a task.
+// Tags with the reserved aws: prefix are left out.
+func serviceTags(ctx context.Context, client *ecs.Client, serviceArn string) ([]types.Tag, error) {
+	output, err := client.ListTagsForResource(ctx, &ecs.ListTagsForResourceInput{
+		ResourceArn: aws.String(serviceArn),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []types.Tag
+	for _, tag := range output.Tags {
+		if !strings.HasPrefix(strings.ToLower(aws.ToString(tag.Key)), "aws:") {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// editTaskOverrides lets the user override container commands, environment
+// variables and the task size before a task is started. It returns nil when
+// nothing was changed.
+func editTaskOverrides(taskDef *types.TaskDefinition) (*types.TaskOverride, error) {
+	commands := make(map[string][]string)
+	environments := make(map[string]map[string]string)
+	var cpu, memory string
+
+	for {
+		// Build the menu with the current settings
+		labels := []string{"Start task"}
+		actions := []func() error{nil}
+
+		for _, c := range taskDef.ContainerDefinitions {
+			name := aws.ToString(c.Name)
+
+			current := "default"
+			if command, ok := commands[name]; ok {
+				current = strings.Join(command, " ")
+			}
+			labels = append(labels, fmt.Sprintf("Command of %s [%s]", name, current))
+			actions = append(actions, func() error {
+				command, err := promptCommand(name, commands[name])
+				if err != nil {
+					return err
+				}
+				if command == nil {
+					delete(commands, name)
+				} else {
+					commands[name] = command
+				}
+				return nil
+			})
+
+			labels = append(labels, fmt.Sprintf("Environment of %s [%d override(s)]", name, len(environments[name])))
+			actions = append(actions, func() error {
+				if environments[name] == nil {
+					environments[name] = make(map[string]string)
+				}
+				return promptEnvironment(name, environments[name])
+			})
+		}
+
+		labels = append(labels, fmt.Sprintf("Task CPU [%s]", overrideLabel(cpu, aws.ToString(taskDef.Cpu))))
+		actions = append(actions, func() (err error) {
+			cpu, err = promptTaskSize("Task CPU units", cpu)
+			return err
+		})
+		labels = append(labels, fmt.Sprintf("Task memory [%s]", overrideLabel(memory, aws.ToString(taskDef.Memory))))
+		actions = append(actions, func() (err error) {
+			memory, err = promptTaskSize("Task memory (MiB)", memory)
+			return err
+		})
+
+		prompt := promptui.Select{
+			Label: "Task settings",
+			Items: labels,
+			Size:  len(labels),
+		}
+		index, _, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		if index == 0 {
+			break
+		}
+		if err := actions[index](); err != nil {
+			return nil, err
+		}
+	}
+
+	overrides := &types.TaskOverride{}
+	if cpu != "" {
+		overrides.Cpu = aws.String(cpu)
+	}
+	if memory != "" {
+		overrides.Memory = aws.String(memory)
+	}
+	for _, c := range taskDef.ContainerDefinitions {
+		name := aws.ToString(c.Name)
+		command, hasCommand := commands[name]
+		if !hasCommand && len(environments[name]) == 0 {
+			continue
+		}
+
+		containerOverride := types.ContainerOverride{
+			Name:    aws.String(name),
+			Command: command,
+		}
+		var keys []string
+		for key := range environments[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			containerOverride.Environment = append(containerOverride.Environment, types.KeyValuePair{
+				Name:  aws.String(key),
+				Value: aws.String(environments[name][key]),
+			})
+		}
+		overrides.ContainerOverrides = append(overrides.ContainerOverrides, containerOverride)
+	}
+
+	if overrides.Cpu == nil && overrides.Memory == nil && len(overrides.ContainerOverrides) == 0 {
+		return nil, nil
+	}
+	return overrides, nil
+}
+
+func overrideLabel(value, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+	if defaultValue != "" {
+		return defaultValue + " (default)"
+	}
+	return "default"
+}
+
+// promptCommand asks for a container command as a JSON array or space
+// separated words. An empty answer restores the default.
+func promptCommand(containerName string, current []string) ([]string, error) {
+	prompt := promptui.Prompt{
+		Label:   fmt.Sprintf("Command of %s (JSON array or words, empty for default)", containerName),
+		Default: strings.Join(current, " "),
+		Validate: func(input string) error {
+			_, err := parseCommand(input)
+			return err
+		},
+	}
+	input, err := prompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	return parseCommand(input)
+}
+
+func parseCommand(input string) ([]string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(input, "[") {
+		var command []string
+		if err := json.Unmarshal([]byte(input), &command); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		return command, nil
+	}
+	return strings.Fields(input), nil
+}
+
+// promptEnvironment edits the environment overrides of a container until
+// an empty line is entered.
+func promptEnvironment(containerName string, environment map[string]string) error {
+	for {
+		prompt := promptui.Prompt{
+			Label: fmt.Sprintf("Environment of %s (NAME=value to set, -NAME to remove, empty to finish)", containerName),
+			Validate: func(input string) error {
+				input = strings.TrimSpace(input)
+				if input == "" || strings.HasPrefix(input, "-") {
+					return nil
+				}
+				if name, _, ok := strings.Cut(input, "="); !ok || name == "" {
+					return fmt.Errorf("expected NAME=value")
+				}
+				return nil
+			},
+		}
+		input, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		input = strings.TrimSpace(input)
+		switch {
+		case input == "":
+			return nil
+		case strings.HasPrefix(input, "-"):
+			delete(environment, strings.TrimPrefix(input, "-"))
+		default:
+			name, value, _ := strings.Cut(input, "=")
+			environment[name] = value
+		}
+	}
+}
+
+// promptTaskSize asks for a CPU or memory value. An empty answer restores
+// the default.
+func promptTaskSize(label, current string) (string, error) {
+	prompt := promptui.Prompt{
+		Label:   label + " (empty for default)",
+		Default: current,
+		Validate: func(input string) error {
+			if input == "" {
+				return nil
+			}
+			if n, err := strconv.Atoi(input); err != nil || n <= 0 {
+				return fmt.Errorf("must be a positive number")
+			}
+			return nil
+		},
+	}
+	value, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(value), nil
+}
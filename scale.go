@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+func runScale(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	parsed, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("invalid task count %q", args[0])
+	}
+	count := int32(parsed)
+
+	// Select profile, cluster and service
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	describeOutput, err := ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(selectedCluster),
+		Services: []string{selectedService},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	if len(describeOutput.Services) == 0 {
+		return fmt.Errorf("service not found: %s", selectedService)
+	}
+	s := describeOutput.Services[0]
+
+	// Auto scaling would move the desired count back into its range
+	scalingClient := applicationautoscaling.NewFromConfig(cfg)
+	resourceID := fmt.Sprintf("service/%s/%s", selectedCluster, selectedService)
+	target, err := findScalableTarget(ctx, scalingClient, resourceID)
+	if err != nil {
+		fmt.Printf("Warning: failed to check auto scaling: %v\n", err)
+	}
+
+	var newMin, newMax int32
+	outOfRange := false
+	if target != nil {
+		minCapacity, maxCapacity := aws.ToInt32(target.MinCapacity), aws.ToInt32(target.MaxCapacity)
+		fmt.Printf("Auto scaling: min %d, max %d\n", minCapacity, maxCapacity)
+
+		if count < minCapacity || count > maxCapacity {
+			outOfRange = true
+			newMin, newMax = minCapacity, maxCapacity
+			if count < newMin {
+				newMin = count
+			}
+			if count > newMax {
+				newMax = count
+			}
+			if scaleAdjust {
+				fmt.Printf("The auto scaling range will be changed to min %d, max %d.\n", newMin, newMax)
+			} else {
+				fmt.Printf("Warning: %d is outside the auto scaling range, so auto scaling may change the desired count back.\n", count)
+				fmt.Println("Use --adjust-limits to change the range as well.")
+			}
+		}
+	}
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Scale service %s in %s from %d to %d task(s)", selectedService, selectedCluster, s.DesiredCount, count),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	if scaleAdjust && outOfRange {
+		_, err := scalingClient.RegisterScalableTarget(ctx, &applicationautoscaling.RegisterScalableTargetInput{
+			ServiceNamespace:  astypes.ServiceNamespaceEcs,
+			ResourceId:        aws.String(resourceID),
+			ScalableDimension: astypes.ScalableDimensionECSServiceDesiredCount,
+			MinCapacity:       aws.Int32(newMin),
+			MaxCapacity:       aws.Int32(newMax),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update auto scaling range: %w", err)
+		}
+		fmt.Printf("Auto scaling range changed to min %d, max %d\n", newMin, newMax)
+	}
+
+	_, err = ecsClient.UpdateService(ctx, &ecs.UpdateServiceInput{
+		Cluster:      aws.String(selectedCluster),
+		Service:      aws.String(selectedService),
+		DesiredCount: aws.Int32(count),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scale service: %w", err)
+	}
+
+	// Wait for the running count to follow
+	return watchDeployment(ctx, ecsClient, selectedCluster, selectedService, "", afterLatestEvent(&s))
+}
+
+// findScalableTarget returns the auto scaling target of a service's desired
+// count, or nil if it has none.
+func findScalableTarget(ctx context.Context, client *applicationautoscaling.Client, resourceID string) (*astypes.ScalableTarget, error) {
+	output, err := client.DescribeScalableTargets(ctx, &applicationautoscaling.DescribeScalableTargetsInput{
+		ServiceNamespace:  astypes.ServiceNamespaceEcs,
+		ResourceIds:       []string{resourceID},
+		ScalableDimension: astypes.ScalableDimensionECSServiceDesiredCount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.ScalableTargets) == 0 {
+		return nil, nil
+	}
+	return &output.ScalableTargets[0], nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunScaleValidatesCount(t *testing.T) {
+	tests := []string{"-1", "two", "1.5", "2147483648", "4294967297"}
+	for _, count := range tests {
+		err := runScale(nil, []string{count})
+		if err == nil || !strings.Contains(err.Error(), "invalid task count") {
+			t.Errorf("runScale(%q) error = %v, want an invalid task count", count, err)
+		}
+	}
+}
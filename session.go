@@ -0,0 +1,681 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// This file implements the client side of the Session Manager data channel
+// protocol, which is what session-manager-plugin speaks with the SSM agent
+// over the websocket returned by ecs:ExecuteCommand and ssm:StartSession.
+
+// Message types
+const (
+	msgInputStreamData  = "input_stream_data"
+	msgOutputStreamData = "output_stream_data"
+	msgAcknowledge      = "acknowledge"
+	msgChannelClosed    = "channel_closed"
+	msgStartPublication = "start_publication"
+	msgPausePublication = "pause_publication"
+)
+
+// Payload types
+const (
+	payloadOutput            uint32 = 1
+	payloadError             uint32 = 2
+	payloadSize              uint32 = 3
+	payloadParameter         uint32 = 4
+	payloadHandshakeRequest  uint32 = 5
+	payloadHandshakeResponse uint32 = 6
+	payloadHandshakeComplete uint32 = 7
+	payloadFlag              uint32 = 10
+	payloadStdErr            uint32 = 11
+	payloadExitCode          uint32 = 12
+)
+
+// Values carried by payloadFlag messages
+const (
+	flagDisconnectToPort   uint32 = 1
+	flagTerminateSession   uint32 = 2
+	flagConnectToPortError uint32 = 3
+)
+
+// Handshake action statuses
+const (
+	actionStatusSuccess     = 1
+	actionStatusFailed      = 2
+	actionStatusUnsupported = 3
+)
+
+const (
+	// sessionClientVersion is reported to the agent. It is kept below 1.1.70
+	// so that port forwarding sessions use the basic (non-multiplexed) mode.
+	sessionClientVersion = "1.1.61.0"
+
+	agentMessageHeaderLength = 116
+	messageTypeLength        = 32
+	streamChunkSize          = 1024
+	resendTimeout            = 2 * time.Second
+	pingInterval             = 5 * time.Minute
+)
+
+// agentMessage is a single binary frame on the data channel.
+type agentMessage struct {
+	MessageType    string
+	SchemaVersion  uint32
+	CreatedDate    uint64
+	SequenceNumber int64
+	Flags          uint64
+	MessageID      [16]byte
+	PayloadType    uint32
+	Payload        []byte
+}
+
+// MarshalBinary encodes the message in the agent's wire format.
+func (m *agentMessage) MarshalBinary() ([]byte, error) {
+	if len(m.MessageType) > messageTypeLength {
+		return nil, fmt.Errorf("message type too long: %s", m.MessageType)
+	}
+
+	buf := make([]byte, agentMessageHeaderLength+4+len(m.Payload))
+	binary.BigEndian.PutUint32(buf[0:4], agentMessageHeaderLength)
+	copy(buf[4:36], strings.Repeat(" ", messageTypeLength))
+	copy(buf[4:36], m.MessageType)
+	binary.BigEndian.PutUint32(buf[36:40], m.SchemaVersion)
+	binary.BigEndian.PutUint64(buf[40:48], m.CreatedDate)
+	binary.BigEndian.PutUint64(buf[48:56], uint64(m.SequenceNumber))
+	binary.BigEndian.PutUint64(buf[56:64], m.Flags)
+	// The agent stores the least significant half of the UUID first
+	copy(buf[64:72], m.MessageID[8:])
+	copy(buf[72:80], m.MessageID[:8])
+	digest := sha256.Sum256(m.Payload)
+	copy(buf[80:112], digest[:])
+	binary.BigEndian.PutUint32(buf[112:116], m.PayloadType)
+	binary.BigEndian.PutUint32(buf[116:120], uint32(len(m.Payload)))
+	copy(buf[120:], m.Payload)
+
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a frame received from the agent.
+func (m *agentMessage) UnmarshalBinary(data []byte) error {
+	if len(data) < agentMessageHeaderLength+4 {
+		return fmt.Errorf("message too short: %d bytes", len(data))
+	}
+
+	headerLength := int(binary.BigEndian.Uint32(data[0:4]))
+	if headerLength < agentMessageHeaderLength || len(data) < headerLength+4 {
+		return fmt.Errorf("invalid header length: %d", headerLength)
+	}
+
+	m.MessageType = strings.TrimRight(string(data[4:36]), " \x00")
+	m.SchemaVersion = binary.BigEndian.Uint32(data[36:40])
+	m.CreatedDate = binary.BigEndian.Uint64(data[40:48])
+	m.SequenceNumber = int64(binary.BigEndian.Uint64(data[48:56]))
+	m.Flags = binary.BigEndian.Uint64(data[56:64])
+	copy(m.MessageID[8:], data[64:72])
+	copy(m.MessageID[:8], data[72:80])
+	m.PayloadType = binary.BigEndian.Uint32(data[112:116])
+
+	payloadLength := int(binary.BigEndian.Uint32(data[headerLength : headerLength+4]))
+	payloadStart := headerLength + 4
+	if len(data) < payloadStart+payloadLength {
+		return fmt.Errorf("payload truncated: want %d bytes, got %d", payloadLength, len(data)-payloadStart)
+	}
+	m.Payload = data[payloadStart : payloadStart+payloadLength]
+
+	digest := sha256.Sum256(m.Payload)
+	if string(digest[:]) != string(data[80:112]) {
+		return fmt.Errorf("payload digest mismatch")
+	}
+
+	return nil
+}
+
+type openDataChannelInput struct {
+	MessageSchemaVersion string
+	RequestId            string
+	TokenValue           string
+	ClientId             string
+	ClientVersion        string
+}
+
+type acknowledgeContent struct {
+	AcknowledgedMessageType           string
+	AcknowledgedMessageId             string
+	AcknowledgedMessageSequenceNumber int64
+	IsSequentialMessage               bool
+}
+
+type handshakeRequest struct {
+	AgentVersion           string
+	RequestedClientActions []struct {
+		ActionType       string
+		ActionParameters json.RawMessage
+	}
+}
+
+type processedClientAction struct {
+	ActionType   string
+	ActionStatus int
+	ActionResult json.RawMessage
+	Error        string
+}
+
+type handshakeResponse struct {
+	ClientVersion          string
+	ProcessedClientActions []processedClientAction
+	Errors                 []string
+}
+
+type handshakeComplete struct {
+	HandshakeTimeToComplete time.Duration
+	CustomerMessage         string
+}
+
+type channelClosed struct {
+	SessionId string
+	Output    string
+}
+
+type pendingMessage struct {
+	raw    []byte
+	sentAt time.Time
+}
+
+// dataChannel is an open Session Manager session. Output from the agent is
+// written to stdout and stderr in sequence order; input is sent with
+// sendInput once the handshake has completed.
+type dataChannel struct {
+	conn   *websocket.Conn
+	stdout io.Writer
+	stderr io.Writer
+
+	writeMu sync.Mutex
+
+	mu           sync.Mutex
+	cond         *sync.Cond
+	nextOutSeq   int64
+	nextInSeq    int64
+	buffered     map[int64]*agentMessage
+	unacked      map[int64]*pendingMessage
+	paused       bool
+	sessionType  string
+	exitCode     int
+	hasExitCode  bool
+	closeMessage string
+	err          error
+
+	handshakeDone chan struct{}
+	handshakeOnce sync.Once
+	done          chan struct{}
+	doneOnce      sync.Once
+}
+
+// openDataChannel connects to streamURL, authenticates with token and starts
+// processing agent messages in the background.
+func openDataChannel(ctx context.Context, streamURL, token string, stdout, stderr io.Writer) (*dataChannel, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, streamURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session stream: %w", err)
+	}
+
+	dc := &dataChannel{
+		conn:          conn,
+		stdout:        stdout,
+		stderr:        stderr,
+		buffered:      make(map[int64]*agentMessage),
+		unacked:       make(map[int64]*pendingMessage),
+		handshakeDone: make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	dc.cond = sync.NewCond(&dc.mu)
+
+	open, err := json.Marshal(openDataChannelInput{
+		MessageSchemaVersion: "1.0",
+		RequestId:            uuidString(newUUID()),
+		TokenValue:           token,
+		ClientId:             uuidString(newUUID()),
+		ClientVersion:        sessionClientVersion,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, open); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open data channel: %w", err)
+	}
+
+	go dc.readLoop()
+	go dc.keepAlive()
+	go func() {
+		select {
+		case <-ctx.Done():
+			dc.finish(ctx.Err())
+		case <-dc.done:
+		}
+	}()
+
+	return dc, nil
+}
+
+// Handshake returns a channel that is closed once the agent has completed
+// the session handshake.
+func (dc *dataChannel) Handshake() <-chan struct{} {
+	return dc.handshakeDone
+}
+
+// Done returns a channel that is closed when the session has ended.
+func (dc *dataChannel) Done() <-chan struct{} {
+	return dc.done
+}
+
+// Wait blocks until the session ends and returns the reason it ended, or
+// nil if the agent closed the channel normally.
+func (dc *dataChannel) Wait() error {
+	<-dc.done
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.err
+}
+
+// ExitCode returns the remote exit code if the agent reported one.
+func (dc *dataChannel) ExitCode() (int, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.exitCode, dc.hasExitCode
+}
+
+// CloseMessage returns the message sent by the agent with channel_closed.
+func (dc *dataChannel) CloseMessage() string {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.closeMessage
+}
+
+// Close ends the session without waiting for the agent.
+func (dc *dataChannel) Close() error {
+	dc.finish(nil)
+	return nil
+}
+
+// sendInput sends data to the agent as input_stream_data, split into chunks
+// the agent accepts. It waits while the agent has paused publication.
+func (dc *dataChannel) sendInput(payloadType uint32, data []byte) error {
+	return dc.sendChunks(payloadType, data, true)
+}
+
+func (dc *dataChannel) sendChunks(payloadType uint32, data []byte, honourPause bool) error {
+	for len(data) > streamChunkSize {
+		if err := dc.sendInputMessage(payloadType, data[:streamChunkSize], honourPause); err != nil {
+			return err
+		}
+		data = data[streamChunkSize:]
+	}
+	return dc.sendInputMessage(payloadType, data, honourPause)
+}
+
+// resizeTerminal tells the agent the size of the local terminal.
+func (dc *dataChannel) resizeTerminal(cols, rows int) error {
+	payload, err := json.Marshal(map[string]int{"cols": cols, "rows": rows})
+	if err != nil {
+		return err
+	}
+	return dc.sendInput(payloadSize, payload)
+}
+
+// sendFlag sends a control flag, used by port forwarding sessions.
+func (dc *dataChannel) sendFlag(flag uint32) error {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, flag)
+	return dc.sendInput(payloadFlag, payload)
+}
+
+func (dc *dataChannel) sendInputMessage(payloadType uint32, data []byte, honourPause bool) error {
+	// Honour flow control requested by the agent
+	dc.mu.Lock()
+	for honourPause && dc.paused && !dc.isDone() {
+		dc.cond.Wait()
+	}
+	dc.mu.Unlock()
+	if dc.isDone() {
+		return io.ErrClosedPipe
+	}
+
+	dc.writeMu.Lock()
+	defer dc.writeMu.Unlock()
+
+	dc.mu.Lock()
+	seq := dc.nextOutSeq
+	dc.nextOutSeq++
+	msg := &agentMessage{
+		MessageType:    msgInputStreamData,
+		SchemaVersion:  1,
+		CreatedDate:    uint64(time.Now().UnixMilli()),
+		SequenceNumber: seq,
+		MessageID:      newUUID(),
+		PayloadType:    payloadType,
+		Payload:        data,
+	}
+	raw, err := msg.MarshalBinary()
+	if err != nil {
+		dc.mu.Unlock()
+		return err
+	}
+	dc.unacked[seq] = &pendingMessage{raw: raw, sentAt: time.Now()}
+	dc.mu.Unlock()
+
+	return dc.conn.WriteMessage(websocket.BinaryMessage, raw)
+}
+
+func (dc *dataChannel) sendAcknowledge(msg *agentMessage) error {
+	content, err := json.Marshal(acknowledgeContent{
+		AcknowledgedMessageType:           msg.MessageType,
+		AcknowledgedMessageId:             uuidString(msg.MessageID),
+		AcknowledgedMessageSequenceNumber: msg.SequenceNumber,
+		IsSequentialMessage:               true,
+	})
+	if err != nil {
+		return err
+	}
+
+	ack := &agentMessage{
+		MessageType:   msgAcknowledge,
+		SchemaVersion: 1,
+		CreatedDate:   uint64(time.Now().UnixMilli()),
+		Flags:         3,
+		MessageID:     newUUID(),
+		Payload:       content,
+	}
+	raw, err := ack.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	dc.writeMu.Lock()
+	defer dc.writeMu.Unlock()
+	return dc.conn.WriteMessage(websocket.BinaryMessage, raw)
+}
+
+func (dc *dataChannel) readLoop() {
+	for {
+		messageType, data, err := dc.conn.ReadMessage()
+		if err != nil {
+			if dc.isDone() || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				dc.finish(nil)
+			} else {
+				dc.finish(fmt.Errorf("session stream closed: %w", err))
+			}
+			return
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+
+		msg := &agentMessage{}
+		if err := msg.UnmarshalBinary(data); err != nil {
+			// Malformed frames are dropped; the agent resends unacknowledged data
+			continue
+		}
+
+		if err := dc.handleMessage(msg); err != nil {
+			dc.finish(err)
+			return
+		}
+	}
+}
+
+func (dc *dataChannel) handleMessage(msg *agentMessage) error {
+	switch msg.MessageType {
+	case msgOutputStreamData:
+		if err := dc.sendAcknowledge(msg); err != nil {
+			return err
+		}
+
+		// Deliver messages strictly in sequence order
+		dc.mu.Lock()
+		if msg.SequenceNumber < dc.nextInSeq {
+			dc.mu.Unlock()
+			return nil
+		}
+		dc.buffered[msg.SequenceNumber] = msg
+		var ready []*agentMessage
+		for {
+			next, ok := dc.buffered[dc.nextInSeq]
+			if !ok {
+				break
+			}
+			delete(dc.buffered, dc.nextInSeq)
+			ready = append(ready, next)
+			dc.nextInSeq++
+		}
+		dc.mu.Unlock()
+
+		for _, m := range ready {
+			if err := dc.handlePayload(m); err != nil {
+				return err
+			}
+		}
+
+	case msgAcknowledge:
+		var ack acknowledgeContent
+		if err := json.Unmarshal(msg.Payload, &ack); err != nil {
+			return nil
+		}
+		dc.mu.Lock()
+		delete(dc.unacked, ack.AcknowledgedMessageSequenceNumber)
+		dc.mu.Unlock()
+
+	case msgPausePublication, msgStartPublication:
+		dc.mu.Lock()
+		dc.paused = msg.MessageType == msgPausePublication
+		dc.cond.Broadcast()
+		dc.mu.Unlock()
+
+	case msgChannelClosed:
+		var closed channelClosed
+		_ = json.Unmarshal(msg.Payload, &closed)
+		dc.mu.Lock()
+		dc.closeMessage = closed.Output
+		dc.mu.Unlock()
+		dc.finish(nil)
+	}
+
+	return nil
+}
+
+func (dc *dataChannel) handlePayload(msg *agentMessage) error {
+	switch msg.PayloadType {
+	case payloadOutput:
+		if _, err := dc.stdout.Write(msg.Payload); err != nil {
+			return err
+		}
+
+	case payloadStdErr:
+		if _, err := dc.stderr.Write(msg.Payload); err != nil {
+			return err
+		}
+
+	case payloadExitCode:
+		code, err := strconv.Atoi(strings.TrimSpace(string(msg.Payload)))
+		if err == nil {
+			dc.mu.Lock()
+			dc.exitCode = code
+			dc.hasExitCode = true
+			dc.mu.Unlock()
+		}
+
+	case payloadHandshakeRequest:
+		return dc.handleHandshake(msg.Payload)
+
+	case payloadHandshakeComplete:
+		var complete handshakeComplete
+		_ = json.Unmarshal(msg.Payload, &complete)
+		if complete.CustomerMessage != "" {
+			fmt.Fprintln(dc.stderr, complete.CustomerMessage)
+		}
+		dc.handshakeOnce.Do(func() { close(dc.handshakeDone) })
+
+	case payloadFlag:
+		if len(msg.Payload) == 4 && binary.BigEndian.Uint32(msg.Payload) == flagConnectToPortError {
+			return fmt.Errorf("the agent could not connect to the remote port")
+		}
+	}
+
+	return nil
+}
+
+func (dc *dataChannel) handleHandshake(payload []byte) error {
+	var request handshakeRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return fmt.Errorf("invalid handshake request: %w", err)
+	}
+
+	response := handshakeResponse{
+		ClientVersion: sessionClientVersion,
+		Errors:        []string{},
+	}
+	var handshakeErr error
+
+	for _, action := range request.RequestedClientActions {
+		processed := processedClientAction{ActionType: action.ActionType}
+
+		switch action.ActionType {
+		case "SessionType":
+			var params struct {
+				SessionType string
+			}
+			_ = json.Unmarshal(action.ActionParameters, &params)
+			dc.mu.Lock()
+			dc.sessionType = params.SessionType
+			dc.mu.Unlock()
+			processed.ActionStatus = actionStatusSuccess
+
+		case "KMSEncryption":
+			handshakeErr = errors.New("the session requires KMS encryption, which ecsy does not support")
+			processed.ActionStatus = actionStatusFailed
+			processed.Error = handshakeErr.Error()
+
+		default:
+			processed.ActionStatus = actionStatusUnsupported
+			processed.Error = fmt.Sprintf("unsupported action %s", action.ActionType)
+		}
+
+		response.ProcessedClientActions = append(response.ProcessedClientActions, processed)
+	}
+
+	content, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	// This runs on the read loop, which is the only thing that can lift a
+	// pause, so the response must not wait for one
+	if err := dc.sendChunks(payloadHandshakeResponse, content, false); err != nil {
+		return err
+	}
+
+	return handshakeErr
+}
+
+// keepAlive resends unacknowledged input and pings the websocket so that
+// idle sessions are not dropped.
+func (dc *dataChannel) keepAlive() {
+	resendTicker := time.NewTicker(resendTimeout / 2)
+	defer resendTicker.Stop()
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-dc.done:
+			return
+
+		case <-pingTicker.C:
+			err := dc.conn.WriteControl(websocket.PingMessage, []byte("keepalive"), time.Now().Add(10*time.Second))
+			if err != nil {
+				dc.finish(fmt.Errorf("session stream closed: %w", err))
+				return
+			}
+
+		case <-resendTicker.C:
+			dc.mu.Lock()
+			var seqs []int64
+			for seq, pending := range dc.unacked {
+				if time.Since(pending.sentAt) >= resendTimeout {
+					seqs = append(seqs, seq)
+				}
+			}
+			sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+			var stale [][]byte
+			for _, seq := range seqs {
+				dc.unacked[seq].sentAt = time.Now()
+				stale = append(stale, dc.unacked[seq].raw)
+			}
+			dc.mu.Unlock()
+
+			dc.writeMu.Lock()
+			for _, raw := range stale {
+				if err := dc.conn.WriteMessage(websocket.BinaryMessage, raw); err != nil {
+					break
+				}
+			}
+			dc.writeMu.Unlock()
+		}
+	}
+}
+
+func (dc *dataChannel) isDone() bool {
+	select {
+	case <-dc.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (dc *dataChannel) finish(err error) {
+	dc.doneOnce.Do(func() {
+		dc.mu.Lock()
+		dc.err = err
+		// Close done before waking paused senders so they see it
+		close(dc.done)
+		dc.cond.Broadcast()
+		dc.mu.Unlock()
+
+		dc.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		dc.conn.Close()
+	})
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() [16]byte {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate UUID: %v", err))
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return u
+}
+
+func uuidString(u [16]byte) string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
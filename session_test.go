@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const testTimeout = 5 * time.Second
+
+// fakeAgent plays the SSM agent side of a data channel.
+type fakeAgent struct {
+	conn   *websocket.Conn
+	open   openDataChannelInput
+	outSeq int64
+}
+
+// startFakeAgent starts a websocket server that accepts one data channel and
+// runs script against it. It returns the URL to connect to.
+func startFakeAgent(t *testing.T, script func(a *fakeAgent) error) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		a := &fakeAgent{conn: conn}
+		conn.SetReadDeadline(time.Now().Add(testTimeout))
+		messageType, data, err := conn.ReadMessage()
+		if err != nil || messageType != websocket.TextMessage {
+			t.Errorf("expected the open data channel message, got type %d: %v", messageType, err)
+			return
+		}
+		if err := json.Unmarshal(data, &a.open); err != nil {
+			t.Errorf("invalid open data channel message: %v", err)
+			return
+		}
+		if err := script(a); err != nil {
+			t.Errorf("agent: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func (a *fakeAgent) send(messageType string, payloadType uint32, seq int64, payload string) error {
+	msg := &agentMessage{
+		MessageType:    messageType,
+		SchemaVersion:  1,
+		CreatedDate:    uint64(time.Now().UnixMilli()),
+		SequenceNumber: seq,
+		MessageID:      newUUID(),
+		PayloadType:    payloadType,
+		Payload:        []byte(payload),
+	}
+	raw, err := msg.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return a.conn.WriteMessage(websocket.BinaryMessage, raw)
+}
+
+// sendOutput sends the next output_stream_data message and waits for the
+// client to acknowledge it.
+func (a *fakeAgent) sendOutput(payloadType uint32, payload string) error {
+	seq := a.outSeq
+	a.outSeq++
+	if err := a.send(msgOutputStreamData, payloadType, seq, payload); err != nil {
+		return err
+	}
+	return a.expectAck(seq)
+}
+
+func (a *fakeAgent) next() (*agentMessage, error) {
+	a.conn.SetReadDeadline(time.Now().Add(testTimeout))
+	for {
+		messageType, data, err := a.conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		msg := &agentMessage{}
+		if err := msg.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+}
+
+func (a *fakeAgent) expectAck(seq int64) error {
+	msg, err := a.next()
+	if err != nil {
+		return err
+	}
+	if msg.MessageType != msgAcknowledge {
+		return fmt.Errorf("got %s, want %s", msg.MessageType, msgAcknowledge)
+	}
+	var ack acknowledgeContent
+	if err := json.Unmarshal(msg.Payload, &ack); err != nil {
+		return err
+	}
+	if ack.AcknowledgedMessageSequenceNumber != seq {
+		return fmt.Errorf("acknowledged %d, want %d", ack.AcknowledgedMessageSequenceNumber, seq)
+	}
+	return nil
+}
+
+// expectInput reads the next input_stream_data message and acknowledges it.
+func (a *fakeAgent) expectInput(payloadType uint32) (*agentMessage, error) {
+	msg, err := a.next()
+	if err != nil {
+		return nil, err
+	}
+	if msg.MessageType != msgInputStreamData || msg.PayloadType != payloadType {
+		return nil, fmt.Errorf("got %s with payload type %d, want %s with payload type %d", msg.MessageType, msg.PayloadType, msgInputStreamData, payloadType)
+	}
+	content, err := json.Marshal(acknowledgeContent{
+		AcknowledgedMessageType:           msg.MessageType,
+		AcknowledgedMessageId:             uuidString(msg.MessageID),
+		AcknowledgedMessageSequenceNumber: msg.SequenceNumber,
+		IsSequentialMessage:               true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, a.send(msgAcknowledge, 0, 0, string(content))
+}
+
+// handshake runs the agent side of the session handshake.
+func (a *fakeAgent) handshake() error {
+	request := `{"AgentVersion":"3.3.0.0","RequestedClientActions":[{"ActionType":"SessionType","ActionParameters":{"SessionType":"InteractiveCommands"}}]}`
+	if err := a.sendOutput(payloadHandshakeRequest, request); err != nil {
+		return err
+	}
+	msg, err := a.expectInput(payloadHandshakeResponse)
+	if err != nil {
+		return err
+	}
+	var response handshakeResponse
+	if err := json.Unmarshal(msg.Payload, &response); err != nil {
+		return err
+	}
+	if len(response.ProcessedClientActions) != 1 || response.ProcessedClientActions[0].ActionStatus != actionStatusSuccess {
+		return fmt.Errorf("unexpected handshake response: %s", msg.Payload)
+	}
+	return a.sendOutput(payloadHandshakeComplete, `{"HandshakeTimeToComplete":1000000}`)
+}
+
+// closeChannel sends channel_closed and waits for the client to hang up.
+func (a *fakeAgent) closeChannel(output string) error {
+	payload, err := json.Marshal(channelClosed{SessionId: "session", Output: output})
+	if err != nil {
+		return err
+	}
+	if err := a.send(msgChannelClosed, 0, 0, string(payload)); err != nil {
+		return err
+	}
+	for {
+		if _, err := a.next(); err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(testTimeout):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestDataChannelSession(t *testing.T) {
+	opened := make(chan openDataChannelInput, 1)
+	outputSent := make(chan struct{})
+	url := startFakeAgent(t, func(a *fakeAgent) error {
+		opened <- a.open
+		if err := a.handshake(); err != nil {
+			return err
+		}
+
+		// Output arriving out of order is delivered in sequence order
+		if err := a.send(msgOutputStreamData, payloadOutput, 3, "world\n"); err != nil {
+			return err
+		}
+		if err := a.expectAck(3); err != nil {
+			return err
+		}
+		a.outSeq = 2
+		if err := a.sendOutput(payloadOutput, "hello "); err != nil {
+			return err
+		}
+		a.outSeq = 4
+		if err := a.sendOutput(payloadStdErr, "oops\n"); err != nil {
+			return err
+		}
+		if err := a.sendOutput(payloadExitCode, "3"); err != nil {
+			return err
+		}
+		close(outputSent)
+
+		msg, err := a.expectInput(payloadOutput)
+		if err != nil {
+			return err
+		}
+		if string(msg.Payload) != "ls\n" {
+			return fmt.Errorf("got input %q, want %q", msg.Payload, "ls\n")
+		}
+		return a.closeChannel("Exiting session with sessionId: session.")
+	})
+
+	var stdout, stderr bytes.Buffer
+	dc, err := openDataChannel(context.Background(), url, "secret-token", &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("openDataChannel() error = %v", err)
+	}
+	defer dc.Close()
+
+	waitFor(t, dc.Handshake(), "the handshake")
+	waitFor(t, outputSent, "the output")
+	if err := dc.sendInput(payloadOutput, []byte("ls\n")); err != nil {
+		t.Fatalf("sendInput() error = %v", err)
+	}
+	waitFor(t, dc.Done(), "channel_closed")
+	if err := dc.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	open := <-opened
+	if open.TokenValue != "secret-token" || open.ClientVersion != sessionClientVersion {
+		t.Errorf("open data channel message = %+v", open)
+	}
+	if got := stdout.String(); got != "hello world\n" {
+		t.Errorf("stdout = %q, want %q", got, "hello world\n")
+	}
+	if got := stderr.String(); got != "oops\n" {
+		t.Errorf("stderr = %q, want %q", got, "oops\n")
+	}
+	if code, ok := dc.ExitCode(); !ok || code != 3 {
+		t.Errorf("ExitCode() = %d, %t, want 3, true", code, ok)
+	}
+	if got := dc.CloseMessage(); got != "Exiting session with sessionId: session." {
+		t.Errorf("CloseMessage() = %q", got)
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if dc.sessionType != "InteractiveCommands" {
+		t.Errorf("sessionType = %q, want InteractiveCommands", dc.sessionType)
+	}
+	if len(dc.unacked) != 0 {
+		t.Errorf("%d input message(s) left unacknowledged", len(dc.unacked))
+	}
+}
+
+func TestDataChannelPausePublication(t *testing.T) {
+	resume := make(chan struct{})
+	url := startFakeAgent(t, func(a *fakeAgent) error {
+		// A pause before the handshake must not hold back the response
+		if err := a.send(msgPausePublication, 0, 0, ""); err != nil {
+			return err
+		}
+		if err := a.handshake(); err != nil {
+			return err
+		}
+
+		select {
+		case <-resume:
+		case <-time.After(testTimeout):
+			return fmt.Errorf("timed out waiting to resume")
+		}
+		if err := a.send(msgStartPublication, 0, 0, ""); err != nil {
+			return err
+		}
+		msg, err := a.expectInput(payloadOutput)
+		if err != nil {
+			return err
+		}
+		if string(msg.Payload) != "x" {
+			return fmt.Errorf("got input %q, want %q", msg.Payload, "x")
+		}
+		return a.closeChannel("")
+	})
+
+	var stdout, stderr bytes.Buffer
+	dc, err := openDataChannel(context.Background(), url, "token", &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("openDataChannel() error = %v", err)
+	}
+	defer dc.Close()
+
+	waitFor(t, dc.Handshake(), "the handshake")
+
+	sent := make(chan struct{})
+	go func() {
+		if err := dc.sendInput(payloadOutput, []byte("x")); err != nil {
+			t.Errorf("sendInput() error = %v", err)
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("input was sent while publication was paused")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(resume)
+	waitFor(t, sent, "input after start_publication")
+	waitFor(t, dc.Done(), "channel_closed")
+	if err := dc.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+}
+
+func TestDataChannelClosedWhilePaused(t *testing.T) {
+	hangUp := make(chan struct{})
+	url := startFakeAgent(t, func(a *fakeAgent) error {
+		if err := a.send(msgPausePublication, 0, 0, ""); err != nil {
+			return err
+		}
+		if err := a.handshake(); err != nil {
+			return err
+		}
+		// Drop the connection without resuming publication
+		select {
+		case <-hangUp:
+		case <-time.After(testTimeout):
+			return fmt.Errorf("timed out waiting to hang up")
+		}
+		return nil
+	})
+
+	var stdout, stderr bytes.Buffer
+	dc, err := openDataChannel(context.Background(), url, "token", &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("openDataChannel() error = %v", err)
+	}
+	defer dc.Close()
+
+	waitFor(t, dc.Handshake(), "the handshake")
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- dc.sendInput(payloadOutput, []byte("x"))
+	}()
+	select {
+	case <-sent:
+		t.Fatal("input was sent while publication was paused")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(hangUp)
+	select {
+	case err := <-sent:
+		if !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("sendInput() error = %v, want %v", err, io.ErrClosedPipe)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("paused input was not released when the channel closed")
+	}
+	if err := dc.Wait(); err == nil {
+		t.Error("Wait() error = nil after the connection dropped")
+	}
+}
+
+func TestAgentMessageBinary(t *testing.T) {
+	msg := &agentMessage{
+		MessageType:    msgInputStreamData,
+		SchemaVersion:  1,
+		CreatedDate:    1700000000000,
+		SequenceNumber: 42,
+		Flags:          3,
+		MessageID:      [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		PayloadType:    payloadOutput,
+		Payload:        []byte("payload"),
+	}
+	raw, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	if len(raw) != agentMessageHeaderLength+4+len(msg.Payload) {
+		t.Fatalf("encoded length = %d", len(raw))
+	}
+	if !bytes.Equal(raw[64:72], msg.MessageID[8:]) || !bytes.Equal(raw[72:80], msg.MessageID[:8]) {
+		t.Errorf("message ID halves are not swapped: % x", raw[64:80])
+	}
+
+	got := &agentMessage{}
+	if err := got.UnmarshalBinary(raw); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if got.MessageType != msg.MessageType || got.SchemaVersion != msg.SchemaVersion ||
+		got.CreatedDate != msg.CreatedDate || got.SequenceNumber != msg.SequenceNumber ||
+		got.Flags != msg.Flags || got.MessageID != msg.MessageID ||
+		got.PayloadType != msg.PayloadType || !bytes.Equal(got.Payload, msg.Payload) {
+		t.Errorf("round trip = %+v, want %+v", got, msg)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"too short", raw[:agentMessageHeaderLength], "too short"},
+		{"truncated payload", raw[:len(raw)-1], "truncated"},
+		{"corrupted payload", append(append([]byte{}, raw[:len(raw)-1]...), 'X'), "digest mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&agentMessage{}).UnmarshalBinary(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("UnmarshalBinary() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	long := &agentMessage{MessageType: strings.Repeat("x", messageTypeLength+1)}
+	if _, err := long.MarshalBinary(); err == nil {
+		t.Error("MarshalBinary() accepted a message type longer than the header field")
+	}
+}
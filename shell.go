@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// autoShell is the --command value that picks the best shell available in
+// the container.
+const autoShell = "auto"
+
+// defaultShells is the probe order when no preference is configured.
+var defaultShells = []string{"bash", "zsh", "ash", "sh"}
+
+// resolveShell returns the command to start an interactive session with.
+// Unless --command is auto it is returned unchanged; otherwise the
+// container is probed for the shells in the service's preference list
+// (shell = ... in ~/.ecsy/config) and the first one found is used.
+func resolveShell(ctx context.Context, cfg aws.Config, clusterName, serviceName, taskID, containerName string) (string, error) {
+	if command != autoShell {
+		return command, nil
+	}
+
+	shells := defaultShells
+	settings, err := loadEcsyConfig()
+	if err != nil {
+		return "", err
+	}
+	if preference := settings.serviceSetting(serviceName, "shell"); preference != "" {
+		shells = strings.FieldsFunc(preference, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+	}
+
+	// Ask the container which of the shells it has
+	var script strings.Builder
+	for _, shell := range shells {
+		fmt.Fprintf(&script, "command -v %s 2>/dev/null || true; ", shellQuote(shell))
+	}
+	var stdout bytes.Buffer
+	exitCode, err := runRemoteCommand(ctx, cfg, clusterName, taskID, containerName, script.String(), nil, &stdout, io.Discard)
+	if errors.Is(err, errNoExitStatus) {
+		return "", fmt.Errorf("no shell found in container %s (%v).\n"+
+			"The image may not include one (e.g. distroless). Run 'ecsy debug' to start a copy of the task with a toolbox container", containerName, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to detect shell: %w", err)
+	}
+	if exitCode != 0 {
+		return "", fmt.Errorf("failed to detect shell: probe exited with status %d", exitCode)
+	}
+
+	found := make(map[string]string)
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		shellPath := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(shellPath, "/") {
+			found[path.Base(shellPath)] = shellPath
+		}
+	}
+	for _, shell := range shells {
+		if shellPath, ok := found[path.Base(shell)]; ok {
+			fmt.Printf("Using shell: %s\n", shellPath)
+			return shellPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("none of %s found in container %s.\n"+
+		"Run 'ecsy debug' to start a copy of the task with a toolbox container", strings.Join(shells, ", "), containerName)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+func runStop(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if strings.TrimSpace(stopReason) == "" {
+		return fmt.Errorf("--reason must not be empty")
+	}
+
+	// Select profile, cluster and service
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+	selectedService, err := selectService(ctx, ecsClient, selectedCluster)
+	if err != nil {
+		return fmt.Errorf("failed to select service: %w", err)
+	}
+
+	tasks, err := describeServiceTasks(ctx, ecsClient, selectedCluster, selectedService)
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("no tasks found for service %s", selectedService)
+	}
+
+	// Task IDs on the command line or --task skip the picker
+	taskIDs := uniqueTaskIDs(args)
+	if len(taskIDs) == 0 && task != "" {
+		taskIDs = []string{task}
+	}
+	if len(taskIDs) == 0 {
+		taskIDs, err = selectTasks(ctx, cfg, selectedCluster, tasks)
+		if err != nil {
+			return fmt.Errorf("failed to select tasks: %w", err)
+		}
+	} else {
+		for _, taskID := range taskIDs {
+			if !containsTask(tasks, taskID) {
+				return fmt.Errorf("task %s is not running in service %s", taskID, selectedService)
+			}
+		}
+	}
+	if len(taskIDs) == 0 {
+		fmt.Println("No tasks selected.")
+		return nil
+	}
+
+	// Stopping every running task at once takes the service down
+	if running := countRunningTasks(tasks); running > 0 && stopsAllRunning(tasks, taskIDs) && !stopForce {
+		return fmt.Errorf("refusing to stop all %d running task(s) of service %s at once (use --force to do it anyway, or 'ecsy restart' to replace them gradually)", running, selectedService)
+	}
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Stop %d of %d task(s) of service %s", len(taskIDs), len(tasks), selectedService),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	// Only show events that come after the stop
+	describeOutput, err := ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(selectedCluster),
+		Services: []string{selectedService},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	var since time.Time
+	if len(describeOutput.Services) > 0 {
+		since = afterLatestEvent(&describeOutput.Services[0])
+	}
+
+	var stopped []string
+	for _, taskID := range taskIDs {
+		if err := stopTask(ctx, ecsClient, selectedCluster, taskID, stopReason); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to stop task %s: %v\n", taskID, err)
+			continue
+		}
+		fmt.Printf("Stopped task %s\n", taskID)
+		stopped = append(stopped, taskID)
+	}
+	if len(stopped) < len(taskIDs) {
+		return fmt.Errorf("failed to stop %d task(s)", len(taskIDs)-len(stopped))
+	}
+	if !stopWait {
+		return nil
+	}
+
+	// Wait for the tasks to stop and the service to replace them
+	fmt.Println("Waiting for the tasks to stop...")
+	waiter := ecs.NewTasksStoppedWaiter(ecsClient)
+	err = waiter.Wait(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(selectedCluster),
+		Tasks:   stopped,
+	}, deployTimeout)
+	if err != nil {
+		return fmt.Errorf("failed waiting for tasks to stop: %w", err)
+	}
+	return watchDeployment(ctx, ecsClient, selectedCluster, selectedService, "", since)
+}
+
+// selectTasks lets the user pick several tasks by toggling them in the task
+// picker until Done is chosen.
+func selectTasks(ctx context.Context, cfg aws.Config, clusterName string, tasks []types.Task) ([]string, error) {
+	// Show recent resource usage with --metrics
+	var usageLabels map[string]string
+	if pickerMetrics {
+		var err error
+		usageLabels, err = taskUsageLabels(ctx, cfg, clusterName, tasks)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	taskIDs := make([]string, len(tasks))
+	labels := make([]string, len(tasks))
+	for i, t := range tasks {
+		taskIDs[i] = taskIDFromArn(aws.ToString(t.TaskArn))
+		labels[i] = fmt.Sprintf("%s (%s)", taskIDs[i], aws.ToString(t.LastStatus))
+		if usage, ok := usageLabels[taskIDs[i]]; ok {
+			labels[i] += "  " + usage
+		}
+	}
+
+	selected := make([]bool, len(tasks))
+	cursor := 0
+	for {
+		count := 0
+		items := make([]string, 0, len(tasks)+1)
+		for i, label := range labels {
+			mark := "[ ]"
+			if selected[i] {
+				mark = "[x]"
+				count++
+			}
+			items = append(items, mark+" "+label)
+		}
+		items = append(items, fmt.Sprintf("Done (%d selected)", count))
+
+		prompt := promptui.Select{
+			Label:        "Select ECS Tasks (Enter toggles)",
+			Items:        items,
+			Size:         10,
+			HideSelected: true,
+		}
+		index, _, err := prompt.RunCursorAt(cursor, cursor-9)
+		if err != nil {
+			return nil, err
+		}
+		if index == len(tasks) {
+			break
+		}
+		selected[index] = !selected[index]
+		cursor = index
+	}
+
+	var result []string
+	for i, taskID := range taskIDs {
+		if selected[i] {
+			result = append(result, taskID)
+		}
+	}
+	return result, nil
+}
+
+// uniqueTaskIDs returns taskIDs without duplicates, in their original order.
+func uniqueTaskIDs(taskIDs []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, taskID := range taskIDs {
+		if !seen[taskID] {
+			seen[taskID] = true
+			result = append(result, taskID)
+		}
+	}
+	return result
+}
+
+func countRunningTasks(tasks []types.Task) int {
+	count := 0
+	for _, t := range tasks {
+		if aws.ToString(t.LastStatus) == "RUNNING" {
+			count++
+		}
+	}
+	return count
+}
+
+// stopsAllRunning reports whether taskIDs include every running task.
+func stopsAllRunning(tasks []types.Task, taskIDs []string) bool {
+	selected := make(map[string]bool)
+	for _, taskID := range taskIDs {
+		selected[taskID] = true
+	}
+	for _, t := range tasks {
+		if aws.ToString(t.LastStatus) == "RUNNING" && !selected[taskIDFromArn(aws.ToString(t.TaskArn))] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTask(tasks []types.Task, taskID string) bool {
+	for _, t := range tasks {
+		if taskIDFromArn(aws.ToString(t.TaskArn)) == taskID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func testTask(id, status string) types.Task {
+	return types.Task{
+		TaskArn:    aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/my-cluster/" + id),
+		LastStatus: aws.String(status),
+	}
+}
+
+func TestStopsAllRunning(t *testing.T) {
+	tasks := []types.Task{
+		testTask("a", "RUNNING"),
+		testTask("b", "RUNNING"),
+		testTask("c", "PENDING"),
+		testTask("d", "PROVISIONING"),
+	}
+	tests := []struct {
+		name    string
+		taskIDs []string
+		want    bool
+	}{
+		{"one of two running", []string{"a"}, false},
+		{"one running and the pending ones", []string{"a", "c", "d"}, false},
+		{"only pending", []string{"c", "d"}, false},
+		{"a duplicate does not count twice", []string{"a", "a"}, false},
+		{"all running", []string{"a", "b"}, true},
+		{"everything", []string{"a", "b", "c", "d"}, true},
+	}
+	for _, tt := range tests {
+		if got := stopsAllRunning(tasks, tt.taskIDs); got != tt.want {
+			t.Errorf("%s: stopsAllRunning(%v) = %t, want %t", tt.name, tt.taskIDs, got, tt.want)
+		}
+	}
+
+	if got := countRunningTasks(tasks); got != 2 {
+		t.Errorf("countRunningTasks() = %d, want 2", got)
+	}
+}
+
+func TestUniqueTaskIDs(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"a", "b"}, []string{"a", "b"}},
+		{[]string{"b", "a", "b", "a"}, []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		if got := uniqueTaskIDs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("uniqueTaskIDs(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestContainsTask(t *testing.T) {
+	tasks := []types.Task{testTask("abc", "RUNNING")}
+	if !containsTask(tasks, "abc") {
+		t.Error("containsTask() did not find a task of the service")
+	}
+	if containsTask(tasks, "ab") {
+		t.Error("containsTask() matched a task ID prefix")
+	}
+}
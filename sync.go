@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// syncFile is what is known about a local file between scans.
+type syncFile struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	localDir := args[0]
+	remote, ok := parseRemotePath(args[1])
+	if !ok || remote.path == "" {
+		return fmt.Errorf("destination must be a container path (container:path)")
+	}
+	if info, err := os.Stat(localDir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", localDir)
+	}
+	if syncInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	for _, pattern := range append(append([]string{}, syncIncludes...), syncExcludes...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	target, err := selectRemoteTarget(ctx, remote.container)
+	if err != nil {
+		return err
+	}
+
+	// Compare against what is already in the container
+	fmt.Fprintf(os.Stderr, "Comparing %s with %s:%s...\n", localDir, target.container, remote.path)
+	remoteHashes, err := fetchRemoteHashes(ctx, target, remote.path)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]*syncFile)
+	changed, err := scanSyncDir(localDir, known)
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, file := range changed {
+		if remoteHashes[file] != known[file].hash {
+			pending = append(pending, file)
+		}
+	}
+
+	if err := pushFiles(ctx, target, localDir, remote.path, pending); err != nil {
+		return err
+	}
+	if !syncWatch {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Watching %s for changes (Ctrl+C to stop)...\n", localDir)
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		changed, err := scanSyncDir(localDir, known)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to scan %s: %v\n", localDir, err)
+			continue
+		}
+		if err := pushFiles(ctx, target, localDir, remote.path, changed); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// Forget the hashes so the files are sent again on the next scan
+			for _, file := range changed {
+				delete(known, file)
+			}
+			fmt.Fprintf(os.Stderr, "Sync failed: %v\n", err)
+		}
+	}
+}
+
+// scanSyncDir walks root and updates known. It returns the files whose
+// content hash changed since the previous scan, relative to root and slash
+// separated. Files that no longer exist are dropped from known.
+func scanSyncDir(root string, known map[string]*syncFile) ([]string, error) {
+	var changed []string
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+
+		if d.IsDir() {
+			if syncExcluded(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || syncExcluded(rel) || !syncIncluded(rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		seen[rel] = true
+
+		// Only hash files whose size or modification time changed
+		previous := known[rel]
+		if previous != nil && previous.size == info.Size() && previous.modTime.Equal(info.ModTime()) {
+			return nil
+		}
+		hash, err := hashFile(filePath)
+		if err != nil {
+			return err
+		}
+		if previous == nil || previous.hash != hash {
+			changed = append(changed, rel)
+		}
+		known[rel] = &syncFile{size: info.Size(), modTime: info.ModTime(), hash: hash}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for rel := range known {
+		if !seen[rel] {
+			delete(known, rel)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// syncExcluded reports whether rel matches an --exclude pattern. Patterns
+// without a slash match any path element, others match the whole path.
+func syncExcluded(rel string) bool {
+	for _, pattern := range syncExcludes {
+		if matchSyncPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncIncluded reports whether rel matches an --include pattern, or true
+// when none are given.
+func syncIncluded(rel string) bool {
+	if len(syncIncludes) == 0 {
+		return true
+	}
+	for _, pattern := range syncIncludes {
+		if matchSyncPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSyncPattern(pattern, rel string) bool {
+	if strings.Contains(pattern, "/") {
+		ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), rel)
+		return ok
+	}
+	for _, element := range strings.Split(rel, "/") {
+		if ok, _ := path.Match(pattern, element); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchRemoteHashes returns the SHA-256 of every file under remoteDir.
+func fetchRemoteHashes(ctx context.Context, target remoteTarget, remoteDir string) (map[string]string, error) {
+	script := fmt.Sprintf(`cd %s 2>/dev/null || exit 0
+command -v sha256sum >/dev/null 2>&1 || exit 0
+find . -type f -exec sha256sum {} +`, shellQuote(remoteDir))
+
+	var stdout bytes.Buffer
+	exitCode, err := runRemoteCommand(ctx, target.cfg, target.cluster, target.task, target.container, script, nil, &stdout, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("failed to list files in %s (exit status %d)", remoteDir, exitCode)
+	}
+
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		hash, file, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			continue
+		}
+		hashes[strings.TrimPrefix(file, "./")] = hash
+	}
+	return hashes, nil
+}
+
+// pushFiles sends files from localDir into remoteDir as a tar archive and
+// runs the post-sync command, if any.
+func pushFiles(ctx context.Context, target remoteTarget, localDir, remoteDir string, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	var archive bytes.Buffer
+	if err := writeTar(&archive, localDir, ".", files); err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	size := archive.Len()
+
+	script := fmt.Sprintf("mkdir -p %s && base64 -d | tar xf - -C %s", shellQuote(remoteDir), shellQuote(remoteDir))
+	if syncPostCommand != "" {
+		script += fmt.Sprintf(" && cd %s && sh -c %s", shellQuote(remoteDir), shellQuote(syncPostCommand))
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(encodeBase64Lines(writer, &archive))
+	}()
+
+	start := time.Now()
+	exitCode, err := runRemoteCommand(ctx, target.cfg, target.cluster, target.task, target.container, script, reader, os.Stdout, os.Stderr)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("remote command exited with status %d", exitCode)
+	}
+
+	summary := strings.Join(files, ", ")
+	if len(files) > 5 {
+		summary = strings.Join(files[:5], ", ") + fmt.Sprintf(" and %d more", len(files)-5)
+	}
+	fmt.Fprintf(os.Stderr, "[%s] Synced %d file(s), %s in %s: %s\n",
+		time.Now().Format("15:04:05"), len(files), formatBytes(int64(size)), time.Since(start).Round(time.Millisecond), summary)
+	return nil
+}
+
+func hashFile(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSyncValidatesFlags(t *testing.T) {
+	dir := t.TempDir()
+	savedInterval := syncInterval
+	t.Cleanup(func() { syncInterval = savedInterval })
+
+	tests := []struct {
+		name     string
+		args     []string
+		interval time.Duration
+		wantErr  string
+	}{
+		{"zero interval", []string{dir, "app:/srv"}, 0, "--interval must be positive"},
+		{"negative interval", []string{dir, "app:/srv"}, -time.Second, "--interval must be positive"},
+		{"local destination", []string{dir, "/srv"}, time.Second, "must be a container path"},
+		{"missing source", []string{filepath.Join(dir, "missing"), "app:/srv"}, time.Second, "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncInterval = tt.interval
+			err := runSync(nil, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runSync() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScanSyncDir(t *testing.T) {
+	savedIncludes, savedExcludes := syncIncludes, syncExcludes
+	t.Cleanup(func() { syncIncludes, syncExcludes = savedIncludes, savedExcludes })
+	syncIncludes = nil
+	syncExcludes = []string{"node_modules", "*.log"}
+
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("app.rb", "puts 1")
+	write("lib/util.rb", "def x; end")
+	write("node_modules/pkg/index.js", "x")
+	write("debug.log", "log")
+
+	known := make(map[string]*syncFile)
+	changed, err := scanSyncDir(root, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"app.rb", "lib/util.rb"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("first scan = %v, want %v", changed, want)
+	}
+
+	// Unchanged files are not reported again
+	changed, err = scanSyncDir(root, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("second scan = %v, want nothing", changed)
+	}
+
+	// Modified content is reported, deleted files are forgotten
+	write("app.rb", "puts 2")
+	if err := os.Chtimes(filepath.Join(root, "app.rb"), time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "lib", "util.rb")); err != nil {
+		t.Fatal(err)
+	}
+	changed, err = scanSyncDir(root, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"app.rb"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("third scan = %v, want %v", changed, want)
+	}
+	if _, ok := known["lib/util.rb"]; ok {
+		t.Error("deleted file is still known")
+	}
+}
+
+func TestMatchSyncPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"*.log", "debug.log", true},
+		{"*.log", "logs/app.log", true},
+		{"node_modules", "web/node_modules/x.js", true},
+		{"tmp/*", "tmp/cache", true},
+		{"/tmp/*", "tmp/cache", true},
+		{"tmp/*", "web/tmp/cache", false},
+		{"*.rb", "app.py", false},
+	}
+	for _, tt := range tests {
+		if got := matchSyncPattern(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("matchSyncPattern(%q, %q) = %t, want %t", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// describeTaskDefinition returns a task definition and its tags.
+func describeTaskDefinition(ctx context.Context, client *ecs.Client, taskDefinition string) (*types.TaskDefinition, []types.Tag, error) {
+	output, err := client.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinition),
+		Include:        []types.TaskDefinitionField{types.TaskDefinitionFieldTags},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to describe task definition: %w", err)
+	}
+	return output.TaskDefinition, output.Tags, nil
+}
+
+// registerInputFromTaskDefinition returns the input that registers a new
+// revision identical to taskDef. Fields set by ECS on registration, such as
+// the revision and status, are left out.
+func registerInputFromTaskDefinition(taskDef *types.TaskDefinition, tags []types.Tag) *ecs.RegisterTaskDefinitionInput {
+	return &ecs.RegisterTaskDefinitionInput{
+		Family:                  taskDef.Family,
+		ContainerDefinitions:    append([]types.ContainerDefinition{}, taskDef.ContainerDefinitions...),
+		Cpu:                     taskDef.Cpu,
+		Memory:                  taskDef.Memory,
+		EphemeralStorage:        taskDef.EphemeralStorage,
+		ExecutionRoleArn:        taskDef.ExecutionRoleArn,
+		TaskRoleArn:             taskDef.TaskRoleArn,
+		InferenceAccelerators:   taskDef.InferenceAccelerators,
+		IpcMode:                 taskDef.IpcMode,
+		PidMode:                 taskDef.PidMode,
+		NetworkMode:             taskDef.NetworkMode,
+		PlacementConstraints:    taskDef.PlacementConstraints,
+		ProxyConfiguration:      taskDef.ProxyConfiguration,
+		RequiresCompatibilities: taskDef.RequiresCompatibilities,
+		RuntimePlatform:         taskDef.RuntimePlatform,
+		Volumes:                 taskDef.Volumes,
+		Tags:                    tags,
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"golang.org/x/term"
+)
+
+// runInteractiveSession attaches the local terminal to a session started by
+// ecs:ExecuteCommand and returns when the remote command exits. If recorder
+// is not nil the session is recorded.
+func runInteractiveSession(ctx context.Context, session *types.Session, recorder *asciicastRecorder) error {
+	if session == nil || session.StreamUrl == nil || session.TokenValue == nil {
+		return fmt.Errorf("no session returned by ExecuteCommand")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stdout io.Writer = os.Stdout
+	if recorder != nil {
+		stdout = io.MultiWriter(os.Stdout, recorder)
+	}
+
+	dc, err := openDataChannel(ctx, aws.ToString(session.StreamUrl), aws.ToString(session.TokenValue), stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer dc.Close()
+
+	// Stop taking keystrokes as soon as the session ends
+	var stdin io.Reader = newStdinReader(dc.Done())
+	if recorder != nil {
+		stdin = io.TeeReader(stdin, recorder.Input())
+	}
+
+	// Put the local terminal into raw mode so keystrokes go to the remote shell
+	stdinFd := int(os.Stdin.Fd())
+	if term.IsTerminal(stdinFd) {
+		oldState, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer term.Restore(stdinFd, oldState)
+	}
+
+	// Wait for the agent to finish the handshake before sending anything
+	select {
+	case <-dc.Handshake():
+	case <-dc.Done():
+		return dc.Wait()
+	}
+
+	go forwardTerminalSize(ctx, dc, recorder)
+	go forwardInput(dc, stdin)
+
+	err = dc.Wait()
+	if message := dc.CloseMessage(); message != "" && err == nil {
+		fmt.Fprint(os.Stderr, message)
+	}
+	return err
+}
+
+// forwardTerminalSize sends the current terminal size and any later changes.
+func forwardTerminalSize(ctx context.Context, dc *dataChannel, recorder *asciicastRecorder) {
+	sendSize := func() {
+		if cols, rows, ok := terminalSize(); ok {
+			dc.resizeTerminal(cols, rows)
+			if recorder != nil {
+				recorder.Resize(cols, rows)
+			}
+		}
+	}
+
+	sendSize()
+	resized := watchTerminalResize(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-dc.Done():
+			return
+		case <-resized:
+			sendSize()
+		}
+	}
+}
+
+// forwardInput copies r to the remote command until r is exhausted or the
+// session ends.
+func forwardInput(dc *dataChannel, r io.Reader) {
+	buf := make([]byte, streamChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			data := make([]byte, n)
+			copy(data, buf[:n])
+			if sendErr := dc.sendInput(payloadOutput, data); sendErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func terminalSize() (int, int, bool) {
+	cols, rows, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || cols <= 0 || rows <= 0 {
+		return 0, 0, false
+	}
+	return cols, rows, true
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchTerminalResize notifies when the local terminal window changes size.
+func watchTerminalResize(ctx context.Context) <-chan struct{} {
+	resized := make(chan struct{}, 1)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				select {
+				case resized <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return resized
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// watchTerminalResize notifies when the local terminal window changes size.
+// Windows has no SIGWINCH, so the size is polled.
+func watchTerminalResize(ctx context.Context) <-chan struct{} {
+	resized := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+
+		lastCols, lastRows, _ := terminalSize()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cols, rows, ok := terminalSize()
+				if !ok || (cols == lastCols && rows == lastRows) {
+					continue
+				}
+				lastCols, lastRows = cols, rows
+				select {
+				case resized <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return resized
+}
@@ -0,0 +1,631 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Kinds of dashboard rows
+const (
+	topServiceRow = iota
+	topTaskRow
+	topContainerRow
+)
+
+// topRow is one line of the dashboard. Task and container rows carry what
+// exec needs.
+type topRow struct {
+	kind      int
+	key       string
+	service   string
+	taskID    string
+	container string
+	values    map[string]string
+}
+
+// topColumn is a dashboard column. Columns with a higher drop priority are
+// hidden first when the terminal is too narrow.
+type topColumn struct {
+	name  string
+	title string
+	drop  int
+	right bool
+}
+
+var topColumns = []topColumn{
+	{name: "name", title: "NAME"},
+	{name: "desired", title: "DESIRED", drop: 4, right: true},
+	{name: "running", title: "RUNNING", drop: 3, right: true},
+	{name: "pending", title: "PENDING", drop: 5, right: true},
+	{name: "status", title: "STATUS", drop: 1},
+	{name: "health", title: "HEALTH", drop: 6},
+	{name: "taskdef", title: "TASK DEFINITION", drop: 8},
+	{name: "ip", title: "IP", drop: 9},
+	{name: "age", title: "AGE", drop: 7},
+}
+
+// Columns up to this drop priority are kept while names can be truncated
+// to at least topMinNameWidth.
+const (
+	topEssentialDrop = 4
+	topMinNameWidth  = 16
+)
+
+// topSnapshot is the cluster state fetched by one refresh.
+type topSnapshot struct {
+	services []types.Service
+	tasks    []types.Task
+	fetched  time.Time
+	err      error
+}
+
+// topView is the state of the dashboard between frames.
+type topView struct {
+	cluster  string
+	snapshot topSnapshot
+	rows     []topRow
+	cursor   int
+	offset   int
+	message  string
+	fetchErr error
+}
+
+func runTop(cmd *cobra.Command, args []string) error {
+	// Stop the resize watcher and any refresh in flight when the dashboard closes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stdinFd := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdinFd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("ecsy top needs a terminal")
+	}
+	if topInterval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s")
+	}
+
+	// Select profile and cluster
+	cfg, ecsClient, selectedCluster, err := selectClusterWithAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	restore, err := enterDashboard(stdinFd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if restore != nil {
+			restore()
+		}
+	}()
+
+	view := &topView{cluster: selectedCluster}
+	snapshots := make(chan topSnapshot, 1)
+	refreshing := false
+	refresh := func() {
+		if refreshing {
+			return
+		}
+		refreshing = true
+		go func() {
+			snapshots <- fetchTopSnapshot(ctx, ecsClient, selectedCluster)
+		}()
+	}
+	refresh()
+
+	ticker := time.NewTicker(topInterval)
+	defer ticker.Stop()
+	resized := watchTerminalResize(ctx)
+	keys := sharedStdin()
+
+	for {
+		drawTop(view)
+
+		select {
+		case snapshot := <-snapshots:
+			refreshing = false
+			view.update(snapshot)
+		case <-ticker.C:
+			refresh()
+		case <-resized:
+		case chunk, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			view.message = ""
+			for _, key := range parseKeys(chunk) {
+				switch key {
+				case "q", "ctrl+c":
+					return nil
+				case "up", "k":
+					view.move(-1)
+				case "down", "j":
+					view.move(1)
+				case "pgup":
+					view.move(-topPageSize())
+				case "pgdown":
+					view.move(topPageSize())
+				case "home", "g":
+					view.move(-len(view.rows))
+				case "end", "G":
+					view.move(len(view.rows))
+				case "r":
+					refresh()
+				case "enter":
+					row, ok := view.execTarget()
+					if !ok {
+						continue
+					}
+
+					// Hand the terminal to the session and come back afterwards
+					restore()
+					sessionErr := execContainer(ctx, cfg, selectedCluster, row.service, row.taskID, row.container)
+					restore, err = enterDashboard(stdinFd)
+					if err != nil {
+						return err
+					}
+					if sessionErr != nil {
+						view.message = sessionErr.Error()
+					}
+					refresh()
+				}
+			}
+		}
+	}
+}
+
+// enterDashboard switches to the alternate screen in raw mode and returns a
+// function that switches back.
+func enterDashboard(fd int) (func(), error) {
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set terminal to raw mode: %w", err)
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+
+	restored := false
+	return func() {
+		if restored {
+			return
+		}
+		restored = true
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		term.Restore(fd, oldState)
+	}, nil
+}
+
+func fetchTopSnapshot(ctx context.Context, client *ecs.Client, clusterName string) topSnapshot {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	services, err := describeClusterServices(ctx, client, clusterName)
+	if err != nil {
+		return topSnapshot{err: err}
+	}
+	tasks, err := listRunningTasks(ctx, client, clusterName)
+	if err != nil {
+		return topSnapshot{err: fmt.Errorf("failed to list tasks in %s: %w", clusterName, err)}
+	}
+	return topSnapshot{services: services, tasks: tasks, fetched: time.Now()}
+}
+
+// update takes a new snapshot and keeps the cursor on the same row. A
+// failed refresh keeps showing the previous state.
+func (v *topView) update(snapshot topSnapshot) {
+	v.fetchErr = snapshot.err
+	if snapshot.err != nil {
+		return
+	}
+	v.snapshot = snapshot
+
+	selected := ""
+	if v.cursor < len(v.rows) {
+		selected = v.rows[v.cursor].key
+	}
+	v.rows = buildTopRows(snapshot)
+	for i, row := range v.rows {
+		if row.key == selected {
+			v.cursor = i
+			return
+		}
+	}
+	v.move(0)
+}
+
+func (v *topView) move(delta int) {
+	v.cursor += delta
+	if v.cursor >= len(v.rows) {
+		v.cursor = len(v.rows) - 1
+	}
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+}
+
+// execTarget returns the container to exec into for the row under the
+// cursor. A task with several containers needs one of its container rows
+// unless --container is given.
+func (v *topView) execTarget() (topRow, bool) {
+	if v.cursor >= len(v.rows) {
+		return topRow{}, false
+	}
+	row := v.rows[v.cursor]
+	switch row.kind {
+	case topServiceRow:
+		v.message = "Select a task to exec into"
+		return topRow{}, false
+	case topContainerRow:
+		return row, true
+	}
+
+	if container != "" {
+		row.container = container
+		return row, true
+	}
+	if row.container == "" {
+		v.message = "Select one of the task's containers"
+		v.move(1)
+		return topRow{}, false
+	}
+	return row, true
+}
+
+// buildTopRows lists each service followed by its tasks, and the containers
+// of tasks with more than one. Tasks outside the services come last.
+func buildTopRows(snapshot topSnapshot) []topRow {
+	tasksByService := make(map[string][]types.Task)
+	for _, t := range snapshot.tasks {
+		name := serviceNameFromGroup(aws.ToString(t.Group))
+		tasksByService[name] = append(tasksByService[name], t)
+	}
+	for _, tasks := range tasksByService {
+		sort.Slice(tasks, func(i, j int) bool {
+			return aws.ToString(tasks[i].TaskArn) < aws.ToString(tasks[j].TaskArn)
+		})
+	}
+
+	services := append([]types.Service{}, snapshot.services...)
+	sort.Slice(services, func(i, j int) bool {
+		return aws.ToString(services[i].ServiceName) < aws.ToString(services[j].ServiceName)
+	})
+
+	var rows []topRow
+	for _, s := range services {
+		name := aws.ToString(s.ServiceName)
+		rows = append(rows, topRow{
+			kind:    topServiceRow,
+			key:     "service/" + name,
+			service: name,
+			values: map[string]string{
+				"name":    name,
+				"desired": fmt.Sprint(s.DesiredCount),
+				"running": fmt.Sprint(s.RunningCount),
+				"pending": fmt.Sprint(s.PendingCount),
+				"status":  serviceRollout(s),
+				"taskdef": taskDefinitionName(aws.ToString(s.TaskDefinition)),
+			},
+		})
+		rows = append(rows, taskRows(name, tasksByService[name])...)
+	}
+
+	// Tasks not started by a service, such as those ecsy starts
+	if service == "" && len(tasksByService[""]) > 0 {
+		rows = append(rows, topRow{
+			kind:   topServiceRow,
+			key:    "service/",
+			values: map[string]string{"name": "(standalone tasks)"},
+		})
+		rows = append(rows, taskRows("", tasksByService[""])...)
+	}
+
+	return rows
+}
+
+func taskRows(serviceName string, tasks []types.Task) []topRow {
+	var rows []topRow
+	for _, t := range tasks {
+		taskID := taskIDFromArn(aws.ToString(t.TaskArn))
+		row := topRow{
+			kind:    topTaskRow,
+			key:     "task/" + taskID,
+			service: serviceName,
+			taskID:  taskID,
+			values: map[string]string{
+				"name":    "  " + taskID,
+				"status":  aws.ToString(t.LastStatus),
+				"health":  string(t.HealthStatus),
+				"taskdef": taskDefinitionName(aws.ToString(t.TaskDefinitionArn)),
+				"ip":      taskPrivateIP(t),
+				"age":     formatAge(t.StartedAt),
+			},
+		}
+		if len(t.Containers) == 1 {
+			row.container = aws.ToString(t.Containers[0].Name)
+		}
+		rows = append(rows, row)
+
+		if len(t.Containers) < 2 {
+			continue
+		}
+		for _, c := range t.Containers {
+			name := aws.ToString(c.Name)
+			rows = append(rows, topRow{
+				kind:      topContainerRow,
+				key:       "container/" + taskID + "/" + name,
+				service:   serviceName,
+				taskID:    taskID,
+				container: name,
+				values: map[string]string{
+					"name":   "    " + name,
+					"status": aws.ToString(c.LastStatus),
+					"health": string(c.HealthStatus),
+				},
+			})
+		}
+	}
+	return rows
+}
+
+// serviceRollout describes the rollout state of a service's primary
+// deployment, noting other deployments still being replaced.
+func serviceRollout(s types.Service) string {
+	state := aws.ToString(s.Status)
+	others := 0
+	for _, d := range s.Deployments {
+		if aws.ToString(d.Status) == "PRIMARY" {
+			if d.RolloutState != "" {
+				state = string(d.RolloutState)
+			}
+		} else {
+			others++
+		}
+	}
+	if others > 0 {
+		state += fmt.Sprintf(" (+%d old)", others)
+	}
+	return state
+}
+
+func formatAge(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	age := time.Since(*t)
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(age.Hours()), int(age.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
+
+// topPageSize is the number of rows a page up or down moves.
+func topPageSize() int {
+	_, rows, ok := terminalSize()
+	if !ok || rows < 8 {
+		return 1
+	}
+	return rows - 5
+}
+
+// drawTop draws the whole dashboard, fitting it into the terminal.
+func drawTop(v *topView) {
+	width, height, ok := terminalSize()
+	if !ok {
+		width, height = 80, 24
+	}
+
+	var lines []string
+	status := "loading..."
+	if !v.snapshot.fetched.IsZero() {
+		status = fmt.Sprintf("%d services, %d tasks, updated %s", len(v.snapshot.services), len(v.snapshot.tasks), v.snapshot.fetched.Format("15:04:05"))
+	}
+	lines = append(lines, fmt.Sprintf("ecsy top - %s - %s", v.cluster, status))
+
+	columns, widths := fitTopColumns(v.rows, width)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.title
+	}
+	lines = append(lines, "\x1b[1m"+formatTopLine(columns, widths, header, width)+"\x1b[0m")
+
+	// Scroll so the cursor stays visible between the header and footer
+	visible := height - 4
+	if visible < 1 {
+		visible = 1
+	}
+	if v.cursor < v.offset {
+		v.offset = v.cursor
+	}
+	if v.cursor >= v.offset+visible {
+		v.offset = v.cursor - visible + 1
+	}
+	if v.offset > len(v.rows)-visible {
+		v.offset = len(v.rows) - visible
+	}
+	if v.offset < 0 {
+		v.offset = 0
+	}
+
+	for i := v.offset; i < len(v.rows) && i < v.offset+visible; i++ {
+		row := v.rows[i]
+		values := make([]string, len(columns))
+		for j, column := range columns {
+			values[j] = row.values[column.name]
+		}
+		line := formatTopLine(columns, widths, values, width)
+		switch {
+		case i == v.cursor:
+			line = "\x1b[7m" + padRight(line, width) + "\x1b[0m"
+		case row.kind == topServiceRow:
+			line = "\x1b[1m" + line + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	if len(v.rows) == 0 && !v.snapshot.fetched.IsZero() {
+		lines = append(lines, "No services found.")
+	}
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	footer := "enter: exec  up/down: move  r: refresh  q: quit"
+	if v.message != "" {
+		footer = v.message
+	} else if v.fetchErr != nil {
+		footer = v.fetchErr.Error()
+	}
+	lines = append(lines[:height-1], truncateText(footer, width))
+
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(line)
+		buf.WriteString("\x1b[K")
+	}
+	os.Stdout.Write(buf.Bytes())
+}
+
+// fitTopColumns drops the least important columns until the rest fit the
+// terminal width, and returns the remaining columns with their widths.
+// Names are truncated if even that is not enough.
+func fitTopColumns(rows []topRow, width int) ([]topColumn, []int) {
+	columns := append([]topColumn{}, topColumns...)
+	widthOf := func(column topColumn) int {
+		w := len(column.title)
+		for _, row := range rows {
+			if n := utf8.RuneCountInString(row.values[column.name]); n > w {
+				w = n
+			}
+		}
+		return w
+	}
+
+	for {
+		widths := make([]int, len(columns))
+		total := 0
+		for i, column := range columns {
+			widths[i] = widthOf(column)
+			total += widths[i] + 2
+		}
+		if total-2 <= width || len(columns) == 1 {
+			if excess := total - 2 - width; excess > 0 {
+				widths[0] -= excess
+			}
+			return columns, widths
+		}
+
+		// Drop the column with the highest drop priority
+		drop := 0
+		for i, column := range columns {
+			if column.drop > columns[drop].drop {
+				drop = i
+			}
+		}
+		// Rather truncate names than lose the counts and status
+		excess := total - 2 - width
+		if columns[drop].drop == 0 || (columns[drop].drop <= topEssentialDrop && widths[0]-excess >= topMinNameWidth) {
+			widths[0] -= excess
+			return columns, widths
+		}
+		columns = append(columns[:drop], columns[drop+1:]...)
+	}
+}
+
+func formatTopLine(columns []topColumn, widths []int, values []string, width int) string {
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		value := truncateText(values[i], widths[i])
+		if column.right {
+			cells[i] = strings.Repeat(" ", widths[i]-utf8.RuneCountInString(value)) + value
+		} else {
+			cells[i] = padRight(value, widths[i])
+		}
+	}
+	return truncateText(strings.TrimRight(strings.Join(cells, "  "), " "), width)
+}
+
+func truncateText(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	if width == 1 {
+		return string(runes[:1])
+	}
+	return string(runes[:width-1]) + "~"
+}
+
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// parseKeys splits a chunk of terminal input into key names.
+func parseKeys(chunk []byte) []string {
+	sequences := []struct {
+		seq  string
+		name string
+	}{
+		{"\x1b[A", "up"}, {"\x1bOA", "up"},
+		{"\x1b[B", "down"}, {"\x1bOB", "down"},
+		{"\x1b[5~", "pgup"}, {"\x1b[6~", "pgdown"},
+		{"\x1b[H", "home"}, {"\x1b[1~", "home"}, {"\x1bOH", "home"},
+		{"\x1b[F", "end"}, {"\x1b[4~", "end"}, {"\x1bOF", "end"},
+	}
+
+	var keys []string
+	for len(chunk) > 0 {
+		matched := false
+		for _, s := range sequences {
+			if bytes.HasPrefix(chunk, []byte(s.seq)) {
+				keys = append(keys, s.name)
+				chunk = chunk[len(s.seq):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		switch chunk[0] {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+		case 0x1b:
+			// Skip the rest of an unknown escape sequence
+			chunk = chunk[len(chunk):]
+			continue
+		default:
+			keys = append(keys, string(chunk[:1]))
+		}
+		chunk = chunk[1:]
+	}
+	return keys
+}
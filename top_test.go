@@ -0,0 +1,173 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		chunk string
+		want  []string
+	}{
+		{"q", []string{"q"}},
+		{"jk\r", []string{"j", "k", "enter"}},
+		{"\n", []string{"enter"}},
+		{"\x03", []string{"ctrl+c"}},
+		{"\x1b[A\x1b[B", []string{"up", "down"}},
+		{"\x1bOA\x1bOB", []string{"up", "down"}},
+		{"\x1b[5~\x1b[6~", []string{"pgup", "pgdown"}},
+		{"\x1b[H\x1b[1~\x1bOH", []string{"home", "home", "home"}},
+		{"\x1b[F\x1b[4~\x1bOF", []string{"end", "end", "end"}},
+		{"g\x1b[99~x", []string{"g"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := parseKeys([]byte(tt.chunk)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKeys(%q) = %q, want %q", tt.chunk, got, tt.want)
+		}
+	}
+}
+
+func TestFitTopColumns(t *testing.T) {
+	rows := []topRow{
+		{values: map[string]string{
+			"name": "my-service", "desired": "2", "running": "2", "pending": "0",
+			"status": "COMPLETED", "taskdef": "my-service:12",
+		}},
+		{values: map[string]string{
+			"name": "  0123456789abcdef0123456789abcdef", "status": "RUNNING", "health": "HEALTHY",
+			"taskdef": "my-service:12", "ip": "10.0.1.23", "age": "3h12m",
+		}},
+	}
+
+	tests := []struct {
+		width   int
+		columns []string
+		widths  []int
+	}{
+		{200, []string{"name", "desired", "running", "pending", "status", "health", "taskdef", "ip", "age"}, []int{34, 7, 7, 7, 9, 7, 15, 9, 5}},
+		{116, []string{"name", "desired", "running", "pending", "status", "health", "taskdef", "ip", "age"}, []int{34, 7, 7, 7, 9, 7, 15, 9, 5}},
+		{115, []string{"name", "desired", "running", "pending", "status", "health", "taskdef", "age"}, []int{34, 7, 7, 7, 9, 7, 15, 5}},
+		{100, []string{"name", "desired", "running", "pending", "status", "health", "age"}, []int{34, 7, 7, 7, 9, 7, 5}},
+		// Names are truncated rather than dropping the counts
+		{60, []string{"name", "desired", "running", "status"}, []int{31, 7, 7, 9}},
+		{30, []string{"name", "status"}, []int{19, 9}},
+		{10, []string{"name"}, []int{10}},
+	}
+	for _, tt := range tests {
+		columns, widths := fitTopColumns(rows, tt.width)
+		names := make([]string, len(columns))
+		total := -2
+		for i, column := range columns {
+			names[i] = column.name
+			total += widths[i] + 2
+		}
+		if !reflect.DeepEqual(names, tt.columns) || !reflect.DeepEqual(widths, tt.widths) {
+			t.Errorf("fitTopColumns(%d) = %v %v, want %v %v", tt.width, names, widths, tt.columns, tt.widths)
+		}
+		if total > tt.width {
+			t.Errorf("fitTopColumns(%d) is %d cells wide", tt.width, total)
+		}
+	}
+}
+
+func TestFormatTopLine(t *testing.T) {
+	columns := []topColumn{{name: "name"}, {name: "running", right: true}, {name: "status"}}
+	widths := []int{6, 3, 7}
+	tests := []struct {
+		values []string
+		width  int
+		want   string
+	}{
+		{[]string{"web", "2", "RUNNING"}, 80, "web       2  RUNNING"},
+		{[]string{"web", "2", ""}, 80, "web       2"},
+		{[]string{"frontend", "12", "PENDING"}, 80, "front~   12  PENDING"},
+		{[]string{"web", "2", "RUNNING"}, 12, "web       2~"},
+		{[]string{"web", "2", "RUNNING"}, 8, "web    ~"},
+	}
+	for _, tt := range tests {
+		if got := formatTopLine(columns, widths, tt.values, tt.width); got != tt.want {
+			t.Errorf("formatTopLine(%q, %d) = %q, want %q", tt.values, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 4, "hel~"},
+		{"hello", 1, "h"},
+		{"hello", 0, ""},
+		{"あいう", 2, "あ~"},
+	}
+	for _, tt := range tests {
+		if got := truncateText(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestServiceRollout(t *testing.T) {
+	tests := []struct {
+		name    string
+		service types.Service
+		want    string
+	}{
+		{
+			name:    "no deployments",
+			service: types.Service{Status: aws.String("ACTIVE")},
+			want:    "ACTIVE",
+		},
+		{
+			name: "completed",
+			service: types.Service{Status: aws.String("ACTIVE"), Deployments: []types.Deployment{
+				{Status: aws.String("PRIMARY"), RolloutState: types.DeploymentRolloutStateCompleted},
+			}},
+			want: "COMPLETED",
+		},
+		{
+			name: "in progress with an old deployment",
+			service: types.Service{Status: aws.String("ACTIVE"), Deployments: []types.Deployment{
+				{Status: aws.String("PRIMARY"), RolloutState: types.DeploymentRolloutStateInProgress},
+				{Status: aws.String("ACTIVE"), RolloutState: types.DeploymentRolloutStateCompleted},
+			}},
+			want: "IN_PROGRESS (+1 old)",
+		},
+	}
+	for _, tt := range tests {
+		if got := serviceRollout(tt.service); got != tt.want {
+			t.Errorf("%s: serviceRollout() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{30 * time.Second, "30s"},
+		{5 * time.Minute, "5m"},
+		{3*time.Hour + 12*time.Minute, "3h12m"},
+		{72 * time.Hour, "3d"},
+	}
+	for _, tt := range tests {
+		started := time.Now().Add(-tt.age - 100*time.Millisecond)
+		if got := formatAge(&started); got != tt.want {
+			t.Errorf("formatAge(%s ago) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+	if got := formatAge(nil); got != "" {
+		t.Errorf("formatAge(nil) = %q, want empty", got)
+	}
+}
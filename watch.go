@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"golang.org/x/term"
+)
+
+// How often a deployment is polled while watching it
+const watchInterval = 5 * time.Second
+
+// watchDeployment shows the progress of a service deployment and the
+// service events since the given time until the service is stable, like
+// the ServicesStable waiter. With a deployment ID it fails as soon as that
+// deployment fails or is replaced, for example by a circuit breaker
+// rollback; without one it follows the current primary deployment.
+func watchDeployment(ctx context.Context, client *ecs.Client, clusterName, serviceName, deploymentID string, since time.Time) error {
+	started := time.Now()
+	progress := newProgressArea(os.Stdout)
+	seenEvents := make(map[string]bool)
+
+	for {
+		output, err := client.DescribeServices(ctx, &ecs.DescribeServicesInput{
+			Cluster:  aws.String(clusterName),
+			Services: []string{serviceName},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe service: %w", err)
+		}
+		if len(output.Services) == 0 {
+			return fmt.Errorf("service not found: %s", serviceName)
+		}
+		s := output.Services[0]
+
+		// Events come newest first
+		var events []string
+		for i := len(s.Events) - 1; i >= 0; i-- {
+			event := s.Events[i]
+			id := aws.ToString(event.Id)
+			if seenEvents[id] || event.CreatedAt == nil || event.CreatedAt.Before(since) {
+				continue
+			}
+			seenEvents[id] = true
+			events = append(events, fmt.Sprintf("%s  %s", formatEventTime(event.CreatedAt), aws.ToString(event.Message)))
+		}
+
+		var status bytes.Buffer
+		fmt.Fprintf(&status, "Service %s: %d desired, %d running, %d pending (%s elapsed)\n",
+			serviceName, s.DesiredCount, s.RunningCount, s.PendingCount, time.Since(started).Round(time.Second))
+		if deploymentConfig := s.DeploymentConfiguration; deploymentConfig != nil {
+			if breaker := deploymentConfig.DeploymentCircuitBreaker; breaker != nil && breaker.Enable {
+				fmt.Fprintf(&status, "Circuit breaker enabled (rollback %t)\n", breaker.Rollback)
+			}
+		}
+		printDeployments(&status, s.Deployments)
+		progress.update(events, strings.Split(strings.TrimRight(status.String(), "\n"), "\n"))
+
+		// Check the deployment being watched
+		stable, err := checkDeployment(s, deploymentID)
+		if err != nil {
+			return err
+		}
+		if stable {
+			fmt.Printf("Service %s is stable (took %s).\n", serviceName, time.Since(started).Round(time.Second))
+			return nil
+		}
+
+		if time.Since(started) > deployTimeout {
+			return fmt.Errorf("timed out after %s waiting for service %s to become stable", deployTimeout, serviceName)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchInterval):
+		}
+	}
+}
+
+// checkDeployment reports whether a service is stable with the watched
+// deployment, or why the deployment will not get there. An empty
+// deploymentID watches the primary deployment.
+func checkDeployment(s types.Service, deploymentID string) (bool, error) {
+	var watched *types.Deployment
+	for i, d := range s.Deployments {
+		if aws.ToString(d.Id) == deploymentID || (deploymentID == "" && aws.ToString(d.Status) == "PRIMARY") {
+			watched = &s.Deployments[i]
+		}
+	}
+
+	switch {
+	case watched == nil && deploymentID == "":
+		return false, fmt.Errorf("service %s has no primary deployment", aws.ToString(s.ServiceName))
+	case watched == nil:
+		return false, fmt.Errorf("deployment %s was replaced by another deployment (rolled back?)", deploymentID)
+	// A failed deployment may already have been replaced by its rollback
+	case watched.RolloutState == types.DeploymentRolloutStateFailed:
+		return false, fmt.Errorf("deployment %s failed: %s", aws.ToString(watched.Id), aws.ToString(watched.RolloutStateReason))
+	case aws.ToString(watched.Status) != "PRIMARY":
+		return false, fmt.Errorf("deployment %s was replaced by another deployment (rolled back?)", aws.ToString(watched.Id))
+	case len(s.Deployments) == 1 && s.RunningCount == s.DesiredCount:
+		return true, nil
+	}
+	return false, nil
+}
+
+// primaryDeploymentID returns the ID of a service's primary deployment.
+func primaryDeploymentID(s *types.Service) string {
+	if s == nil {
+		return ""
+	}
+	for _, d := range s.Deployments {
+		if aws.ToString(d.Status) == "PRIMARY" {
+			return aws.ToString(d.Id)
+		}
+	}
+	return ""
+}
+
+// deploymentStart returns when the primary deployment of a service was
+// created, which unlike the local clock can be compared with event times.
+func deploymentStart(s *types.Service, fallback time.Time) time.Time {
+	if s == nil {
+		return fallback
+	}
+	for _, d := range s.Deployments {
+		if aws.ToString(d.Status) == "PRIMARY" && d.CreatedAt != nil {
+			return *d.CreatedAt
+		}
+	}
+	return fallback
+}
+
+// afterLatestEvent returns a cutoff for watchDeployment that skips the
+// events a service already has, for changes that don't start a deployment.
+// Like deploymentStart it uses the service's clock rather than the local one.
+func afterLatestEvent(s *types.Service) time.Time {
+	var latest time.Time
+	if s == nil {
+		return latest
+	}
+	for _, event := range s.Events {
+		if event.CreatedAt != nil && event.CreatedAt.After(latest) {
+			latest = *event.CreatedAt
+		}
+	}
+	if latest.IsZero() {
+		return latest
+	}
+	// Events are shown unless they are before the cutoff
+	return latest.Add(time.Nanosecond)
+}
+
+// progressArea keeps a status block at the bottom of a terminal, redrawing
+// it in place with new lines printed above it. When out is not a terminal
+// lines are printed as they come and the status only when it changes.
+type progressArea struct {
+	out        io.Writer
+	live       bool
+	drawnLines int
+	lastStatus string
+}
+
+func newProgressArea(f *os.File) *progressArea {
+	return &progressArea{out: f, live: term.IsTerminal(int(f.Fd()))}
+}
+
+func (p *progressArea) update(lines, status []string) {
+	if !p.live {
+		for _, line := range lines {
+			fmt.Fprintln(p.out, line)
+		}
+		// Skip the first line as its elapsed time always changes
+		text := strings.Join(status[1:], "\n")
+		if text != p.lastStatus {
+			fmt.Fprintln(p.out, strings.Join(status, "\n"))
+			p.lastStatus = text
+		}
+		return
+	}
+
+	// Long lines would wrap and throw off the cursor movement
+	width, _, ok := terminalSize()
+	if !ok {
+		width = 80
+	}
+
+	var buf bytes.Buffer
+	if p.drawnLines > 0 {
+		fmt.Fprintf(&buf, "\x1b[%dA\r\x1b[J", p.drawnLines)
+	}
+	for _, line := range lines {
+		fmt.Fprintln(&buf, line)
+	}
+	for _, line := range status {
+		fmt.Fprintln(&buf, truncateText(line, width-1))
+	}
+	p.drawnLines = len(status)
+	p.out.Write(buf.Bytes())
+}
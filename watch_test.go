@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestCheckDeployment(t *testing.T) {
+	primary := func(id string, state types.DeploymentRolloutState) types.Deployment {
+		return types.Deployment{Id: aws.String(id), Status: aws.String("PRIMARY"), RolloutState: state}
+	}
+	active := func(id string, state types.DeploymentRolloutState) types.Deployment {
+		return types.Deployment{Id: aws.String(id), Status: aws.String("ACTIVE"), RolloutState: state, RolloutStateReason: aws.String("tasks failed to start")}
+	}
+
+	tests := []struct {
+		name         string
+		service      types.Service
+		deploymentID string
+		stable       bool
+		wantErr      string
+	}{
+		{
+			name: "stable",
+			service: types.Service{DesiredCount: 2, RunningCount: 2, Deployments: []types.Deployment{
+				primary("ecs-svc/1", types.DeploymentRolloutStateCompleted),
+			}},
+			deploymentID: "ecs-svc/1",
+			stable:       true,
+		},
+		{
+			name: "still replacing the old deployment",
+			service: types.Service{DesiredCount: 2, RunningCount: 3, Deployments: []types.Deployment{
+				primary("ecs-svc/2", types.DeploymentRolloutStateInProgress),
+				active("ecs-svc/1", types.DeploymentRolloutStateCompleted),
+			}},
+			deploymentID: "ecs-svc/2",
+		},
+		{
+			name: "tasks still starting",
+			service: types.Service{DesiredCount: 2, RunningCount: 1, Deployments: []types.Deployment{
+				primary("ecs-svc/1", types.DeploymentRolloutStateInProgress),
+			}},
+			deploymentID: "ecs-svc/1",
+		},
+		{
+			name: "failed and rolled back",
+			service: types.Service{DesiredCount: 2, RunningCount: 2, Deployments: []types.Deployment{
+				primary("ecs-svc/3", types.DeploymentRolloutStateInProgress),
+				active("ecs-svc/2", types.DeploymentRolloutStateFailed),
+			}},
+			deploymentID: "ecs-svc/2",
+			wantErr:      "deployment ecs-svc/2 failed: tasks failed to start",
+		},
+		{
+			name: "replaced by another deployment",
+			service: types.Service{Deployments: []types.Deployment{
+				primary("ecs-svc/3", types.DeploymentRolloutStateInProgress),
+				active("ecs-svc/2", types.DeploymentRolloutStateInProgress),
+			}},
+			deploymentID: "ecs-svc/2",
+			wantErr:      "deployment ecs-svc/2 was replaced",
+		},
+		{
+			name: "gone",
+			service: types.Service{Deployments: []types.Deployment{
+				primary("ecs-svc/3", types.DeploymentRolloutStateCompleted),
+			}},
+			deploymentID: "ecs-svc/2",
+			wantErr:      "deployment ecs-svc/2 was replaced",
+		},
+		{
+			name: "primary deployment failed without an ID",
+			service: types.Service{Deployments: []types.Deployment{
+				{Id: aws.String("ecs-svc/4"), Status: aws.String("PRIMARY"), RolloutState: types.DeploymentRolloutStateFailed, RolloutStateReason: aws.String("circuit breaker")},
+			}},
+			wantErr: "deployment ecs-svc/4 failed: circuit breaker",
+		},
+		{
+			name:    "no primary deployment without an ID",
+			service: types.Service{ServiceName: aws.String("web")},
+			wantErr: "service web has no primary deployment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stable, err := checkDeployment(tt.service, tt.deploymentID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("checkDeployment() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkDeployment() error = %v", err)
+			}
+			if stable != tt.stable {
+				t.Errorf("checkDeployment() = %t, want %t", stable, tt.stable)
+			}
+		})
+	}
+}
+
+func TestDeploymentStart(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fallback := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+	s := &types.Service{Deployments: []types.Deployment{
+		{Id: aws.String("ecs-svc/1"), Status: aws.String("ACTIVE"), CreatedAt: aws.Time(fallback)},
+		{Id: aws.String("ecs-svc/2"), Status: aws.String("PRIMARY"), CreatedAt: aws.Time(created)},
+	}}
+
+	if got := deploymentStart(s, fallback); !got.Equal(created) {
+		t.Errorf("deploymentStart() = %s, want %s", got, created)
+	}
+	if got := deploymentStart(nil, fallback); !got.Equal(fallback) {
+		t.Errorf("deploymentStart(nil) = %s, want %s", got, fallback)
+	}
+	if got := primaryDeploymentID(s); got != "ecs-svc/2" {
+		t.Errorf("primaryDeploymentID() = %q, want ecs-svc/2", got)
+	}
+	if got := primaryDeploymentID(nil); got != "" {
+		t.Errorf("primaryDeploymentID(nil) = %q, want empty", got)
+	}
+}
+
+func TestProgressAreaNotLive(t *testing.T) {
+	var out bytes.Buffer
+	p := &progressArea{out: &out}
+
+	p.update([]string{"event 1"}, []string{"Service web (1s elapsed)", "PRIMARY 1/2"})
+	p.update(nil, []string{"Service web (6s elapsed)", "PRIMARY 1/2"})
+	p.update([]string{"event 2"}, []string{"Service web (11s elapsed)", "PRIMARY 2/2"})
+
+	want := "event 1\nService web (1s elapsed)\nPRIMARY 1/2\n" +
+		"event 2\nService web (11s elapsed)\nPRIMARY 2/2\n"
+	if got := out.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAfterLatestEvent(t *testing.T) {
+	older := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	latest := time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)
+	s := &types.Service{Events: []types.ServiceEvent{
+		{Id: aws.String("2"), CreatedAt: aws.Time(latest)},
+		{Id: aws.String("1"), CreatedAt: aws.Time(older)},
+		{Id: aws.String("0")},
+	}}
+
+	cutoff := afterLatestEvent(s)
+	if !latest.Before(cutoff) {
+		t.Errorf("afterLatestEvent() = %s, want after the latest event %s", cutoff, latest)
+	}
+	if newer := latest.Add(time.Millisecond); newer.Before(cutoff) {
+		t.Errorf("afterLatestEvent() = %s skips a newer event at %s", cutoff, newer)
+	}
+
+	// Without events every new event is shown
+	if got := afterLatestEvent(&types.Service{}); !got.IsZero() {
+		t.Errorf("afterLatestEvent() without events = %s, want zero", got)
+	}
+	if got := afterLatestEvent(nil); !got.IsZero() {
+		t.Errorf("afterLatestEvent(nil) = %s, want zero", got)
+	}
+}